@@ -4,29 +4,58 @@ package backend
 
 import (
 	"bytes"
+	"io"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/samuelngs/semver/pkg/env"
 )
 
+// leaseBucket is the bucket of leases, keyed by `<id>:<path>`
+var leaseBucket = []byte("leases")
+
 // Bolt backend for semver
 type Bolt struct {
 	*Core
 	db *bolt.DB
+
+	// File is the database file, SEMVER_BACKEND_ADDR when empty
+	File string
+
+	quit chan struct{}
+	wg   sync.WaitGroup
 }
 
 // Init method
 func (b *Bolt) Init() error {
+	file := b.File
+	if file == "" {
+		file = env.Raw("SEMVER_BACKEND_ADDR", "local.db")
+	}
 	db, err := bolt.Open(
-		env.Raw("SEMVER_BACKEND_ADDR", "local.db"),
+		file,
 		0600,
-		nil,
+		&bolt.Options{
+			// fail instead of blocking forever when another process holds the file lock
+			Timeout:         env.Duration("SEMVER_BOLT_TIMEOUT", time.Second),
+			InitialMmapSize: env.Int("SEMVER_BOLT_MMAP_SIZE", 0),
+		},
 	)
 	if err != nil {
 		return err
 	}
+	db.NoSync = env.Bool("SEMVER_BOLT_NO_SYNC")
 	b.db = db
+	// expired leases are never returned, the reaper only reclaims their space
+	if interval := env.Duration("SEMVER_BOLT_REAPER_INTERVAL", time.Minute); interval > 0 {
+		b.quit = make(chan struct{})
+		b.wg.Add(1)
+		go b.reaper(interval)
+	}
 	return nil
 }
 
@@ -110,8 +139,9 @@ func (b *Bolt) List(key *Key) ([]*Key, error) {
 			return ErrRecordNotFound
 		}
 		cursor := bucket.Cursor()
-		prefix := []byte(b.Path(key))
-		if len(prefix) > 0 {
+		if path := b.Path(key); path != "" {
+			// the records under the path, not siblings sharing its name prefix
+			prefix := []byte(path + ":")
 			for k, _ := cursor.Seek(prefix); bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
 				keys = append(keys, &Key{ID: key.ID, Dirs: strings.Split(string(k[:]), ":")})
 			}
@@ -159,3 +189,273 @@ func (b *Bolt) Delete(keys ...*Key) error {
 		return nil
 	})
 }
+
+// Apply method, checks and writes run in a single update transaction
+func (b *Bolt) Apply(checks []*Check, ops []*Op) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for _, check := range checks {
+			var val string
+			if bucket := tx.Bucket([]byte(check.Key.ID)); bucket != nil {
+				val = string(bucket.Get([]byte(b.Path(check.Key))))
+			}
+			if val != check.Val {
+				return ErrConflict
+			}
+		}
+		for _, op := range ops {
+			bucket, err := tx.CreateBucketIfNotExists([]byte(op.Key.ID))
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(b.Path(op.Key)), []byte(op.Val)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Incr method
+func (b *Bolt) Incr(key *Key, delta int64) (int64, error) {
+	var n int64
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(key.ID))
+		if err != nil {
+			return err
+		}
+		path := []byte(b.Path(key))
+		if v := bucket.Get(path); len(v) > 0 {
+			if n, err = strconv.ParseInt(string(v), 10, 64); err != nil {
+				return err
+			}
+		}
+		n += delta
+		return bucket.Put(path, []byte(strconv.FormatInt(n, 10)))
+	})
+	return n, err
+}
+
+// Count method
+func (b *Bolt) Count() (int, error) {
+	var count int
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, bucket *bolt.Bucket) error {
+			if bucket.Get([]byte("version")) != nil {
+				count++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Ping method
+func (b *Bolt) Ping() error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return nil
+	})
+}
+
+// Stats method
+func (b *Bolt) Stats() map[string]interface{} {
+	stats := b.db.Stats()
+	return map[string]interface{}{
+		"path":           b.db.Path(),
+		"free_pages":     stats.FreePageN,
+		"pending_pages":  stats.PendingPageN,
+		"free_alloc":     stats.FreeAlloc,
+		"freelist_inuse": stats.FreelistInuse,
+		"read_tx":        stats.TxN,
+		"open_read_tx":   stats.OpenTxN,
+	}
+}
+
+// Backup method writes a consistent copy of the database while it is in use
+func (b *Bolt) Backup(w io.Writer) (int64, error) {
+	var n int64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return n, err
+}
+
+// lease key and value, the value is `<expiry unix nano>:<val>`
+func (b *Bolt) lease(key *Key) []byte {
+	return []byte(key.ID + ":" + b.Path(key))
+}
+
+// expiry parses a lease value
+func expiry(v []byte) (time.Time, string) {
+	parts := strings.SplitN(string(v), ":", 2)
+	n, _ := strconv.ParseInt(parts[0], 10, 64)
+	if len(parts) < 2 {
+		return time.Unix(0, n), ""
+	}
+	return time.Unix(0, n), parts[1]
+}
+
+// Lease method
+func (b *Bolt) Lease(key *Key, val string, ttl time.Duration) (bool, error) {
+	ok := false
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(leaseBucket)
+		if err != nil {
+			return err
+		}
+		k := b.lease(key)
+		if v := bucket.Get(k); v != nil {
+			if expires, _ := expiry(v); time.Now().Before(expires) {
+				return nil
+			}
+		}
+		ok = true
+		expires := time.Now().Add(ttl).UnixNano()
+		return bucket.Put(k, []byte(strconv.FormatInt(expires, 10)+":"+val))
+	})
+	return ok, err
+}
+
+// Leases method
+func (b *Bolt) Leases(key *Key) ([]*Lease, error) {
+	leases := []*Lease{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(leaseBucket)
+		if bucket == nil {
+			return nil
+		}
+		prefix := []byte(key.ID + ":")
+		if path := b.Path(key); path != "" {
+			prefix = append(prefix, path+":"...)
+		}
+		now := time.Now()
+		cursor := bucket.Cursor()
+		for k, v := cursor.Seek(prefix); bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			expires, val := expiry(v)
+			if !now.Before(expires) {
+				continue
+			}
+			dirs := strings.Split(strings.TrimPrefix(string(k), key.ID+":"), ":")
+			leases = append(leases, &Lease{Key: &Key{ID: key.ID, Dirs: dirs}, Val: val, Expires: expires})
+		}
+		return nil
+	})
+	return leases, err
+}
+
+// Revoke method
+func (b *Bolt) Revoke(keys ...*Key) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(leaseBucket)
+		if bucket == nil {
+			return nil
+		}
+		for _, key := range keys {
+			if err := bucket.Delete(b.lease(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// reap removes expired leases
+func (b *Bolt) reap() error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(leaseBucket)
+		if bucket == nil {
+			return nil
+		}
+		now := time.Now()
+		var expired [][]byte
+		bucket.ForEach(func(k, v []byte) error {
+			if expires, _ := expiry(v); !now.Before(expires) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// reaper removes expired leases periodically until the database is closed
+func (b *Bolt) reaper(interval time.Duration) {
+	defer b.wg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-b.quit:
+			return
+		case <-t.C:
+			b.reap()
+		}
+	}
+}
+
+// Close method
+func (b *Bolt) Close() error {
+	if b.db == nil {
+		return nil
+	}
+	if b.quit != nil {
+		close(b.quit)
+		b.wg.Wait()
+		b.quit = nil
+	}
+	return b.db.Close()
+}
+
+// CompactBolt rewrites the database at src into dst, dropping the free pages
+// left behind by deleted projects. The source must not be in use.
+func CompactBolt(src, dst string) error {
+	from, err := bolt.Open(src, 0600, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer from.Close()
+	if _, err := os.Stat(dst); err == nil {
+		return os.ErrExist
+	}
+	to, err := bolt.Open(dst, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	defer to.Close()
+	return from.View(func(rtx *bolt.Tx) error {
+		return rtx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			// one write transaction per project keeps memory usage bound
+			return to.Update(func(wtx *bolt.Tx) error {
+				dest, err := wtx.CreateBucket(name)
+				if err != nil {
+					return err
+				}
+				return copyBucket(bucket, dest)
+			})
+		})
+	})
+}
+
+// copyBucket copies keys and nested buckets
+func copyBucket(src, dst *bolt.Bucket) error {
+	dst.FillPercent = 1.0
+	return src.ForEach(func(k, v []byte) error {
+		if v == nil {
+			nested, err := dst.CreateBucket(k)
+			if err != nil {
+				return err
+			}
+			return copyBucket(src.Bucket(k), nested)
+		}
+		return dst.Put(k, v)
+	})
+}
@@ -0,0 +1,118 @@
+// +build !appengine
+
+package backend
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// openBolt opens a client on a temporary file
+func openBolt(t *testing.T) (*Bolt, func()) {
+	dir, err := ioutil.TempDir("", "semver-backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Bolt{File: filepath.Join(dir, "semver.db")}
+	if err := b.Init(); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return b, func() {
+		b.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestBolt(t *testing.T) {
+	b, done := openBolt(t)
+	defer done()
+	testClient(t, b)
+}
+
+func TestBoltLayout(t *testing.T) {
+	b, done := openBolt(t)
+	defer done()
+	if err := b.Set("1.0.0", &Key{ID: "p", Dirs: []string{"version"}}, &Key{ID: "p", Dirs: []string{"components", "api", "version"}}); err != nil {
+		t.Fatal(err)
+	}
+	// a project of counters only is not counted
+	if _, err := b.Incr(&Key{ID: "q", Dirs: []string{"counters", "build", "value"}}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Lease(&Key{ID: "p", Dirs: []string{"reservations", "1.1.0"}}, "token", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// every project is a bucket of records keyed by their path, leases share
+	// one bucket keyed by `<id>:<path>`
+	layout := make(map[string][]string)
+	var expires time.Time
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			return bucket.ForEach(func(k, v []byte) error {
+				layout[string(name)] = append(layout[string(name)], string(k)+"="+string(v))
+				if string(name) == string(leaseBucket) {
+					expires, _ = expiry(v)
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"p":      "components:api:version=1.0.0 version=1.0.0",
+		"q":      "counters:build:value=1",
+		"leases": "p:reservations:1.1.0=" + strconv.FormatInt(expires.UnixNano(), 10) + ":token",
+	}
+	if len(layout) != len(want) {
+		t.Errorf("buckets = %v", layout)
+	}
+	for name, records := range want {
+		if got := strings.Join(layout[name], " "); got != records {
+			t.Errorf("bucket %s = %q, want %q", name, got, records)
+		}
+	}
+	if d := time.Until(expires); d <= 0 || d > time.Minute {
+		t.Errorf("lease expires in %v", d)
+	}
+	if n, err := b.Count(); err != nil || n != 1 {
+		t.Errorf("Count = %d, %v, want 1", n, err)
+	}
+	if _, err := b.List(&Key{ID: "r"}); err != ErrRecordNotFound {
+		t.Errorf("List of a missing project = %v, want %v", err, ErrRecordNotFound)
+	}
+}
+
+func TestBoltReap(t *testing.T) {
+	b, done := openBolt(t)
+	defer done()
+	for name, ttl := range map[string]time.Duration{"1.1.0": time.Millisecond, "1.2.0": time.Minute} {
+		if _, err := b.Lease(&Key{ID: "p", Dirs: []string{"reservations", name}}, "token", ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := b.reap(); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(leaseBucket).ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	if len(keys) != 1 || keys[0] != "p:reservations:1.2.0" {
+		t.Errorf("leases after reap = %v", keys)
+	}
+}
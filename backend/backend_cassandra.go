@@ -1,21 +1,73 @@
 package backend
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gocql/gocql"
 	"github.com/samuelngs/semver/pkg/env"
 )
 
-var (
-	cbegin = "BEGIN BATCH"
-	cend   = "APPLY BATCH"
-)
+// maxIncrAttempts bounds the compare-and-set attempts of Incr under contention
+const maxIncrAttempts = 10
 
 // Cassandra backend for semver
 type Cassandra struct {
 	*Core
 	cluster *gocql.ClusterConfig
+	session *gocql.Session
+	read    gocql.Consistency
+	write   gocql.Consistency
+
+	// the project count scans the whole table, it is cached for countTTL
+	countTTL time.Duration
+	countMu  sync.Mutex
+	count    int
+	counted  time.Time
+}
+
+// consistency parses a consistency level without panicking on invalid input
+func consistency(s string) (c gocql.Consistency, err error) {
+	defer func() {
+		if o := recover(); o != nil {
+			err = fmt.Errorf("cassandra: invalid consistency %q", s)
+		}
+	}()
+	return gocql.ParseConsistency(s), nil
+}
+
+// replication builds the keyspace replication map from
+// SEMVER_CASSANDRA_STRATEGY, SEMVER_CASSANDRA_REPLICATION_FACTOR and
+// SEMVER_CASSANDRA_DATACENTERS (dc1:3,dc2:2)
+func replication() (string, error) {
+	switch strategy := env.Raw("SEMVER_CASSANDRA_STRATEGY", "SimpleStrategy"); strategy {
+	case "SimpleStrategy":
+		factor := env.Int("SEMVER_CASSANDRA_REPLICATION_FACTOR", 3)
+		return fmt.Sprintf("{'class': 'SimpleStrategy', 'replication_factor': %d}", factor), nil
+	case "NetworkTopologyStrategy":
+		dcs := env.Raw("SEMVER_CASSANDRA_DATACENTERS")
+		if dcs == "" {
+			return "", fmt.Errorf("cassandra: NetworkTopologyStrategy requires SEMVER_CASSANDRA_DATACENTERS")
+		}
+		parts := []string{"'class': 'NetworkTopologyStrategy'"}
+		for _, dc := range strings.Split(dcs, ",") {
+			kv := strings.SplitN(strings.TrimSpace(dc), ":", 2)
+			if len(kv) != 2 {
+				return "", fmt.Errorf("cassandra: invalid datacenter %q, expected name:factor", dc)
+			}
+			factor, err := strconv.Atoi(kv[1])
+			if err != nil || factor <= 0 || strings.ContainsAny(kv[0], "'{}") {
+				return "", fmt.Errorf("cassandra: invalid datacenter %q, expected name:factor", dc)
+			}
+			parts = append(parts, fmt.Sprintf("'%s': %d", kv[0], factor))
+		}
+		return "{" + strings.Join(parts, ", ") + "}", nil
+	default:
+		return "", fmt.Errorf("cassandra: unsupported replication strategy %q", strategy)
+	}
 }
 
 // Init method
@@ -27,24 +79,57 @@ func (c *Cassandra) Init() error {
 	)
 	// database or keyspace name
 	keyspace := env.Raw("SEMVER_BACKEND_DB", "semver")
+	if strings.ContainsAny(keyspace, "\"'; ") {
+		return fmt.Errorf("cassandra: invalid keyspace %q", keyspace)
+	}
+	repl, err := replication()
+	if err != nil {
+		return err
+	}
+	if c.read, err = consistency(env.Raw("SEMVER_CASSANDRA_READ_CONSISTENCY", "QUORUM")); err != nil {
+		return err
+	}
+	if c.write, err = consistency(env.Raw("SEMVER_CASSANDRA_WRITE_CONSISTENCY", "QUORUM")); err != nil {
+		return err
+	}
+	c.countTTL = env.Duration("SEMVER_CASSANDRA_COUNT_TTL", 5*time.Minute)
 	// create cassandra cluster client
 	c.cluster = gocql.NewCluster(addr...)
-	c.cluster.Keyspace = keyspace
-	c.cluster.Consistency = gocql.Quorum
+	c.cluster.Consistency = c.write
 	c.cluster.ProtoVersion = 4
+	c.cluster.RetryPolicy = &gocql.SimpleRetryPolicy{NumRetries: env.Int("SEMVER_BACKEND_RETRIES", 3)}
+	if user := env.Raw("SEMVER_BACKEND_USERNAME"); user != "" {
+		c.cluster.Authenticator = gocql.PasswordAuthenticator{
+			Username: user,
+			Password: env.Raw("SEMVER_BACKEND_PASSWORD"),
+		}
+	}
+	if env.Bool("SEMVER_CASSANDRA_TLS") {
+		c.cluster.SslOpts = &gocql.SslOptions{
+			CaPath:                 env.Raw("SEMVER_CASSANDRA_TLS_CA"),
+			CertPath:               env.Raw("SEMVER_CASSANDRA_TLS_CERT"),
+			KeyPath:                env.Raw("SEMVER_CASSANDRA_TLS_KEY"),
+			EnableHostVerification: env.Bool("SEMVER_CASSANDRA_TLS_VERIFY", true),
+		}
+	}
+	// create keyspace and tables if they are not existed
 	session, err := c.cluster.CreateSession()
 	if err != nil {
 		return err
 	}
 	defer session.Close()
-	// create keyspace and tables if they are not existed
-	if err := session.Query(`CREATE KEYSPACE IF NOT EXISTS ? WITH replication = {'class': 'SimpleStrategy', 'replication_factor' : 3}`, keyspace).Exec(); err != nil {
+	if err := session.Query(fmt.Sprintf(`CREATE KEYSPACE IF NOT EXISTS %s WITH replication = %s`, keyspace, repl)).Exec(); err != nil {
 		return err
 	}
-	if err := session.Query(`USE ?`, keyspace).Exec(); err != nil {
+	if err := session.Query(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.db (id text, key text, val text, PRIMARY KEY (id, key))`, keyspace)).Exec(); err != nil {
 		return err
 	}
-	if err := session.Query(`CREATE TABLE IF NOT EXISTS db (id text, key text, val text, PRIMARY KEY (id, key))`).Exec(); err != nil {
+	if err := session.Query(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.leases (id text, key text, val text, PRIMARY KEY (id, key))`, keyspace)).Exec(); err != nil {
+		return err
+	}
+	// long-lived session bound to the keyspace
+	c.cluster.Keyspace = keyspace
+	if c.session, err = c.cluster.CreateSession(); err != nil {
 		return err
 	}
 	return nil
@@ -69,13 +154,8 @@ func (c *Cassandra) Path(key *Key) string {
 
 // Exists method
 func (c *Cassandra) Exists(key *Key) (bool, error) {
-	session, err := c.cluster.CreateSession()
-	if err != nil {
-		return false, err
-	}
-	defer session.Close()
 	var count int
-	if err := session.Query(`SELECT COUNT(*) FROM db WHERE id = ?`, key.ID).Consistency(gocql.One).Scan(&count); err != nil {
+	if err := c.session.Query(`SELECT COUNT(*) FROM db WHERE id = ?`, key.ID).Consistency(c.read).Scan(&count); err != nil {
 		return false, err
 	}
 	if count > 0 {
@@ -86,59 +166,24 @@ func (c *Cassandra) Exists(key *Key) (bool, error) {
 
 // Set method
 func (c *Cassandra) Set(v string, keys ...*Key) error {
-	var query []string
-	var args []interface{}
-	var count int
-	session, err := c.cluster.CreateSession()
-	if err != nil {
-		return err
-	}
-	defer session.Close()
-	query = make([]string, len(keys)+2)
-	query[count] = cbegin
-	args = make([]interface{}, len(keys)*3)
-	count++
-	for i, key := range keys {
-		k := c.Path(key)
-		args[i*3] = key.ID
-		args[i*3+1] = k
-		args[i*3+2] = v
-		query[count] = `INSERT INTO db (id, key, val) VALUES (?, ?, ?)`
-		count++
-	}
-	query[count] = cend
-	if err := session.Query(
-		strings.Join(query, "\n"),
-		args...,
-	).Exec(); err != nil {
-		return err
+	batch := c.session.NewBatch(gocql.LoggedBatch)
+	batch.Cons = c.write
+	for _, key := range keys {
+		batch.Query(`INSERT INTO db (id, key, val) VALUES (?, ?, ?)`, key.ID, c.Path(key), v)
 	}
-	return nil
+	return c.session.ExecuteBatch(batch)
 }
 
 // Get method
 func (c *Cassandra) Get(keys ...*Key) ([]string, error) {
-	session, err := c.cluster.CreateSession()
-	if err != nil {
-		return nil, err
-	}
-	defer session.Close()
 	res := []string{}
 	ids := make(map[string][]string)
 	for _, key := range keys {
-		var keys []string
-		if _, ok := ids[key.ID]; ok {
-			keys = ids[key.ID]
-		} else {
-			keys = []string{}
-		}
-		k := c.Path(key)
-		keys = append(keys, k)
-		ids[key.ID] = keys
+		ids[key.ID] = append(ids[key.ID], c.Path(key))
 	}
 	for i, o := range ids {
 		var s string
-		iter := session.Query(`SELECT val FROM db WHERE id = ? AND key in ?`, i, o).Iter()
+		iter := c.session.Query(`SELECT val FROM db WHERE id = ? AND key in ?`, i, o).Consistency(c.read).Iter()
 		for iter.Scan(&s) {
 			res = append(res, s)
 		}
@@ -149,21 +194,19 @@ func (c *Cassandra) Get(keys ...*Key) ([]string, error) {
 	return res, nil
 }
 
-// List method
+// List method, children of a path are selected with a clustering key range
+// as '0' is the character following the '/' separator
 func (c *Cassandra) List(key *Key) ([]*Key, error) {
-	session, err := c.cluster.CreateSession()
-	if err != nil {
-		return nil, err
-	}
-	defer session.Close()
+	var iter *gocql.Iter
 	keys := []*Key{}
-	path := c.Path(key)
-	iter := session.Query(`SELECT key FROM db WHERE id = ?`, key.ID).Iter()
+	if path := c.Path(key); path == "" {
+		iter = c.session.Query(`SELECT key FROM db WHERE id = ?`, key.ID).Consistency(c.read).Iter()
+	} else {
+		iter = c.session.Query(`SELECT key FROM db WHERE id = ? AND key > ? AND key < ?`, key.ID, path+"/", path+"0").Consistency(c.read).Iter()
+	}
 	var k string
 	for iter.Scan(&k) {
-		if strings.HasPrefix(k, path) {
-			keys = append(keys, &Key{ID: key.ID, Dirs: strings.Split(k, "/")})
-		}
+		keys = append(keys, &Key{ID: key.ID, Dirs: strings.Split(k, "/")})
 	}
 	if err := iter.Close(); err != nil {
 		return nil, err
@@ -173,35 +216,198 @@ func (c *Cassandra) List(key *Key) ([]*Key, error) {
 
 // Delete method
 func (c *Cassandra) Delete(keys ...*Key) error {
-	session, err := c.cluster.CreateSession()
+	ids := make(map[string][]string)
+	for _, key := range keys {
+		ids[key.ID] = append(ids[key.ID], c.Path(key))
+	}
+	batch := c.session.NewBatch(gocql.LoggedBatch)
+	batch.Cons = c.write
+	for id, dirs := range ids {
+		batch.Query(`DELETE FROM db WHERE id = ? AND key in ?`, id, dirs)
+	}
+	return c.session.ExecuteBatch(batch)
+}
+
+// Apply method, the checks and writes are sent in one conditional batch so
+// they are all applied or none. Lightweight transactions cannot span
+// partitions, ErrNotSupported is returned when the records belong to more
+// than one project.
+func (c *Cassandra) Apply(checks []*Check, ops []*Op) error {
+	ids := make(map[string]bool)
+	conds := make(map[string]string, len(checks))
+	for _, check := range checks {
+		ids[check.Key.ID] = true
+		conds[c.Path(check.Key)] = check.Val
+	}
+	for _, op := range ops {
+		ids[op.Key.ID] = true
+	}
+	if len(ids) == 0 {
+		return nil
+	} else if len(ids) > 1 {
+		return ErrNotSupported
+	}
+	batch := c.session.NewBatch(gocql.LoggedBatch)
+	batch.Cons = c.write
+	for _, op := range ops {
+		path := c.Path(op.Key)
+		val, ok := conds[path]
+		delete(conds, path)
+		switch {
+		case !ok:
+			batch.Query(`INSERT INTO db (id, key, val) VALUES (?, ?, ?)`, op.Key.ID, path, op.Val)
+		case val == "":
+			batch.Query(`UPDATE db SET val = ? WHERE id = ? AND key = ? IF val = null`, op.Val, op.Key.ID, path)
+		default:
+			batch.Query(`UPDATE db SET val = ? WHERE id = ? AND key = ? IF val = ?`, op.Val, op.Key.ID, path, val)
+		}
+	}
+	// checks of records that are not written rewrite the expected value
+	for _, check := range checks {
+		path := c.Path(check.Key)
+		val, ok := conds[path]
+		if !ok {
+			continue
+		}
+		delete(conds, path)
+		if val == "" {
+			batch.Query(`DELETE val FROM db WHERE id = ? AND key = ? IF val = null`, check.Key.ID, path)
+		} else {
+			batch.Query(`UPDATE db SET val = ? WHERE id = ? AND key = ? IF val = ?`, val, check.Key.ID, path, val)
+		}
+	}
+	applied, iter, err := c.session.ExecuteBatchCAS(batch)
 	if err != nil {
 		return err
 	}
-	defer session.Close()
-	ids := make(map[string][]string)
+	if err := iter.Close(); err != nil {
+		return err
+	}
+	if !applied {
+		return ErrConflict
+	}
+	return nil
+}
+
+// Incr method, the record is updated with a lightweight transaction and
+// retried while concurrent writers change it in between, ErrConflict is
+// returned after maxIncrAttempts
+func (c *Cassandra) Incr(key *Key, delta int64) (int64, error) {
+	path := c.Path(key)
+	for attempt := 0; attempt < maxIncrAttempts; attempt++ {
+		var val string
+		err := c.session.Query(`SELECT val FROM db WHERE id = ? AND key = ?`, key.ID, path).Consistency(c.read).Scan(&val)
+		if err != nil && err != gocql.ErrNotFound {
+			return 0, err
+		}
+		var n int64
+		found := err == nil
+		if found && val != "" {
+			if n, err = strconv.ParseInt(val, 10, 64); err != nil {
+				return 0, err
+			}
+		}
+		next := strconv.FormatInt(n+delta, 10)
+		var q *gocql.Query
+		if !found {
+			q = c.session.Query(`INSERT INTO db (id, key, val) VALUES (?, ?, ?) IF NOT EXISTS`, key.ID, path, next)
+		} else {
+			q = c.session.Query(`UPDATE db SET val = ? WHERE id = ? AND key = ? IF val = ?`, next, key.ID, path, val)
+		}
+		applied, err := q.Consistency(c.write).MapScanCAS(map[string]interface{}{})
+		if err != nil {
+			return 0, err
+		}
+		if applied {
+			return n + delta, nil
+		}
+	}
+	return 0, ErrConflict
+}
+
+// Lease method, leases are rows of the leases table written with a ttl
+func (c *Cassandra) Lease(key *Key, val string, ttl time.Duration) (bool, error) {
+	// ttls are whole seconds, round up so a lease never expires early
+	secs := int((ttl + time.Second - 1) / time.Second)
+	return c.session.Query(`INSERT INTO leases (id, key, val) VALUES (?, ?, ?) IF NOT EXISTS USING TTL ?`, key.ID, c.Path(key), val, secs).
+		Consistency(c.write).
+		MapScanCAS(map[string]interface{}{})
+}
+
+// Leases method
+func (c *Cassandra) Leases(key *Key) ([]*Lease, error) {
+	var iter *gocql.Iter
+	if path := c.Path(key); path == "" {
+		iter = c.session.Query(`SELECT key, val, TTL(val) FROM leases WHERE id = ?`, key.ID).Consistency(c.read).Iter()
+	} else {
+		iter = c.session.Query(`SELECT key, val, TTL(val) FROM leases WHERE id = ? AND key > ? AND key < ?`, key.ID, path+"/", path+"0").Consistency(c.read).Iter()
+	}
+	leases := []*Lease{}
+	var k, val string
+	var ttl int
+	for iter.Scan(&k, &val, &ttl) {
+		leases = append(leases, &Lease{
+			Key:     &Key{ID: key.ID, Dirs: strings.Split(k, "/")},
+			Val:     val,
+			Expires: time.Now().Add(time.Duration(ttl) * time.Second),
+		})
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
+
+// Revoke method, leases are removed with lightweight transactions as they
+// are created with them
+func (c *Cassandra) Revoke(keys ...*Key) error {
 	for _, key := range keys {
-		if _, ok := ids[key.ID]; !ok {
-			ids[key.ID] = []string{}
+		if _, err := c.session.Query(`DELETE FROM leases WHERE id = ? AND key = ? IF EXISTS`, key.ID, c.Path(key)).
+			Consistency(c.write).
+			MapScanCAS(map[string]interface{}{}); err != nil {
+			return err
 		}
-		path := c.Path(key)
-		ids[key.ID] = append(ids[key.ID], path)
 	}
-	queries := make([]string, len(ids)+2)
-	args := make([]interface{}, len(ids)*2)
-	count := 0
-	queries[count] = cbegin
-	queries[len(queries)-1] = cend
-	for id, dirs := range ids {
-		queries[count+1] = `DELETE FROM db WHERE id = ? AND key in ?`
-		args[count*2] = id
-		args[count*2+1] = dirs
-		count++
-	}
-	if err := session.Query(
-		strings.Join(queries, "\n"),
-		args...,
-	).Exec(); err != nil {
-		return err
+	return nil
+}
+
+// Count method, the count is cached for SEMVER_CASSANDRA_COUNT_TTL
+func (c *Cassandra) Count() (int, error) {
+	c.countMu.Lock()
+	defer c.countMu.Unlock()
+	if !c.counted.IsZero() && time.Since(c.counted) < c.countTTL {
+		return c.count, nil
+	}
+	var count int
+	if err := c.session.Query(`SELECT COUNT(*) FROM db WHERE key = 'version' ALLOW FILTERING`).Consistency(gocql.One).Scan(&count); err != nil {
+		return 0, err
+	}
+	c.count, c.counted = count, time.Now()
+	return count, nil
+}
+
+// Ping method
+func (c *Cassandra) Ping() error {
+	var version string
+	return c.session.Query(`SELECT release_version FROM system.local`).Consistency(gocql.One).Scan(&version)
+}
+
+// Stats method
+func (c *Cassandra) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"hosts":             c.cluster.Hosts,
+		"keyspace":          c.cluster.Keyspace,
+		"num_conns":         c.cluster.NumConns,
+		"read_consistency":  c.read.String(),
+		"write_consistency": c.write.String(),
+		"session_closed":    c.session.Closed(),
+	}
+}
+
+// Close method
+func (c *Cassandra) Close() error {
+	if c.session != nil {
+		c.session.Close()
 	}
 	return nil
 }
@@ -0,0 +1,27 @@
+package backend
+
+import (
+	"os"
+	"testing"
+)
+
+// TestCassandra runs against the cluster at SEMVER_TEST_CASSANDRA_ADDR, e.g.
+// `docker run -p 9042:9042 cassandra`, in the semver_test keyspace
+func TestCassandra(t *testing.T) {
+	addr := os.Getenv("SEMVER_TEST_CASSANDRA_ADDR")
+	if addr == "" {
+		t.Skip("SEMVER_TEST_CASSANDRA_ADDR is not set")
+	}
+	defer setenv(map[string]string{
+		"SEMVER_BACKEND_ADDR":                 addr,
+		"SEMVER_BACKEND_DB":                   "semver_test",
+		"SEMVER_CASSANDRA_REPLICATION_FACTOR": "1",
+		"SEMVER_CASSANDRA_COUNT_TTL":          "0s",
+	})()
+	c := new(Cassandra)
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	testClient(t, c)
+}
@@ -2,40 +2,111 @@ package backend
 
 import (
 	"encoding/json"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/samuelngs/semver/pkg/env"
 
 	"golang.org/x/net/context"
 	"golang.org/x/oauth2/google"
 
-	"google.golang.org/appengine"
 	"google.golang.org/cloud"
 	"google.golang.org/cloud/datastore"
 )
 
+// datastore kinds, every project is an entity group rooted at its Semver
+// entity, records are child entities named after their path
+const (
+	projectKind = "Semver"
+	recordKind  = "Record"
+	leaseKind   = "Lease"
+)
+
+// leaseEntity is a lease in the project entity group, datastore has no
+// expiry so expired leases are skipped and overwritten
+type leaseEntity struct {
+	Value   string `datastore:",noindex"`
+	Expires time.Time
+}
+
 // GceDatastore backend for semver
 type GceDatastore struct {
 	*Core
+	client  *datastore.Client
+	ctx     context.Context
+	project string
 }
 
-// client creates gce client
-func (d *GceDatastore) storage() (context.Context, *datastore.Client, error) {
-	key := []byte(env.Raw("SEMVER_BACKEND_TOKEN"))
-	conf, err := google.JWTConfigFromJSON(key, datastore.ScopeDatastore)
-	if err != nil {
-		return nil, nil, err
+// Init method creates the datastore client once, it connects to the local
+// emulator when DATASTORE_EMULATOR_HOST is set
+func (d *GceDatastore) Init() error {
+	d.ctx = context.Background()
+	if ns := env.Raw("SEMVER_DATASTORE_NAMESPACE"); ns != "" {
+		d.ctx = datastore.WithNamespace(d.ctx, ns)
 	}
-	ctx := appengine.BackgroundContext()
-	client, err := datastore.NewClient(ctx, appengine.AppID(ctx), cloud.WithTokenSource(conf.TokenSource(ctx)))
+	d.project = env.Raw("SEMVER_BACKEND_DB", os.Getenv("DATASTORE_PROJECT_ID"))
+	var opts []cloud.ClientOption
+	if os.Getenv("DATASTORE_EMULATOR_HOST") == "" {
+		key := []byte(env.Raw("SEMVER_BACKEND_TOKEN"))
+		conf, err := google.JWTConfigFromJSON(key, datastore.ScopeDatastore)
+		if err != nil {
+			return err
+		}
+		if d.project == "" {
+			var account struct {
+				ProjectID string `json:"project_id"`
+			}
+			json.Unmarshal(key, &account)
+			d.project = account.ProjectID
+		}
+		opts = append(opts, cloud.WithTokenSource(conf.TokenSource(d.ctx)))
+	}
+	client, err := datastore.NewClient(d.ctx, d.project, opts...)
 	if err != nil {
-		return nil, nil, err
+		return err
 	}
-	return ctx, client, nil
+	d.client = client
+	if env.Bool("SEMVER_DATASTORE_MIGRATE") {
+		return d.migrate()
+	}
+	return nil
 }
 
-// Init method
-func (d *GceDatastore) Init() error {
+// migrate splits projects stored by earlier releases as a single JSON blob
+// into records, it is safe to run more than once
+func (d *GceDatastore) migrate() error {
+	var projects []*Project
+	keys, err := d.client.GetAll(d.ctx, datastore.NewQuery(projectKind), &projects)
+	if err != nil {
+		return err
+	}
+	for i, p := range projects {
+		if p.Data == "" {
+			continue
+		}
+		var legacy struct {
+			Version string            `json:"version"`
+			Archive map[string]string `json:"archive"`
+		}
+		if err := json.Unmarshal([]byte(p.Data), &legacy); err != nil {
+			return err
+		}
+		id := keys[i].Name()
+		_, err := d.client.RunInTransaction(d.ctx, func(tx *datastore.Transaction) error {
+			for path, val := range legacy.Archive {
+				if _, err := tx.Put(d.record(id, path), &Record{Value: val}); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Put(d.root(id), &Project{Version: legacy.Version, Updated: time.Now().UTC()})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -56,192 +127,352 @@ func (d *GceDatastore) Path(key *Key) string {
 	return dir
 }
 
+// root returns the project entity key
+func (d *GceDatastore) root(id string) *datastore.Key {
+	return datastore.NewKey(d.ctx, projectKind, id, 0, nil)
+}
+
+// record returns the key of a record in the project entity group
+func (d *GceDatastore) record(id, path string) *datastore.Key {
+	return datastore.NewKey(d.ctx, recordKind, path, 0, d.root(id))
+}
+
+// records lists the record keys of a project, tx may be nil
+func (d *GceDatastore) records(id string, tx *datastore.Transaction) ([]*datastore.Key, error) {
+	q := datastore.NewQuery(recordKind).Ancestor(d.root(id)).KeysOnly()
+	if tx != nil {
+		q = q.Transaction(tx)
+	}
+	return d.client.GetAll(d.ctx, q, nil)
+}
+
 // Exists method
 func (d *GceDatastore) Exists(key *Key) (bool, error) {
-	var e Entity
-	ctx, client, err := d.storage()
-	if err != nil {
-		return false, err
-	}
-	k := datastore.NewKey(ctx, "Semver", key.ID, 0, nil)
-	if err := client.Get(ctx, k, &e); err != nil && err != datastore.ErrNoSuchEntity {
-		return false, err
-	} else if err != nil && err == datastore.ErrNoSuchEntity {
+	var p Project
+	if err := d.client.Get(d.ctx, d.root(key.ID), &p); err == datastore.ErrNoSuchEntity {
 		return false, nil
+	} else if err != nil {
+		return false, err
 	}
 	return true, nil
 }
 
-// Set method
+// Set method, the version pointer and the records are written in one
+// transaction so concurrent bumps cannot interleave
 func (d *GceDatastore) Set(val string, keys ...*Key) error {
-	ctx, client, err := d.storage()
-	if err != nil {
-		return err
-	}
-	// entity cache
-	cache := make(map[string]*Entity)
-	for _, key := range keys {
-		var e *Entity
-		var v *Versioning
-		if o, ok := cache[key.ID]; ok {
-			e = o
-		} else {
-			k := datastore.NewKey(ctx, "Semver", key.ID, 0, nil)
-			if err := client.Get(ctx, k, &e); err != nil && err != datastore.ErrNoSuchEntity {
+	_, err := d.client.RunInTransaction(d.ctx, func(tx *datastore.Transaction) error {
+		projects := make(map[string]*Project)
+		for _, key := range keys {
+			p, ok := projects[key.ID]
+			if !ok {
+				p = new(Project)
+				if err := tx.Get(d.root(key.ID), p); err != nil && err != datastore.ErrNoSuchEntity {
+					return err
+				}
+				projects[key.ID] = p
+			}
+			if path := d.Path(key); path == "version" {
+				p.Version = val
+			} else if _, err := tx.Put(d.record(key.ID, path), &Record{Value: val}); err != nil {
 				return err
 			}
 		}
-		if e == nil {
-			e = new(Entity)
-		}
-		if e.Data != "" {
-			if err := json.Unmarshal([]byte(e.Data), &v); err != nil {
+		for id, p := range projects {
+			p.Updated = time.Now().UTC()
+			if _, err := tx.Put(d.root(id), p); err != nil {
 				return err
 			}
-		} else {
-			v = &Versioning{Archive: make(map[string]string)}
-		}
-		p := d.Path(key)
-		if p == "version" {
-			v.Version = val
-		} else {
-			v.Archive[p] = val
-		}
-		b, err := json.Marshal(v)
-		if err != nil {
-			return err
 		}
-		e.Data = string(b[:])
-		cache[key.ID] = e
-	}
-	for i, e := range cache {
-		k := datastore.NewKey(ctx, "Semver", i, 0, nil)
-		if _, err := client.Put(ctx, k, e); err != nil {
-			return err
-		}
-	}
-	return nil
+		return nil
+	})
+	return err
 }
 
 // Get method
 func (d *GceDatastore) Get(keys ...*Key) ([]string, error) {
-	ctx, client, err := d.storage()
-	if err != nil {
-		return nil, err
-	}
-	// entity cache
-	cache := make(map[string]*Entity)
-	// return values
-	store := []string{}
+	projects := make(map[string]*Project)
+	vals := []string{}
 	for _, key := range keys {
-		var e *Entity
-		var v *Versioning
-		if o, ok := cache[key.ID]; ok {
-			e = o
-		} else {
-			k := datastore.NewKey(ctx, "Semver", key.ID, 0, nil)
-			if err := client.Get(ctx, k, &e); err != nil && err != datastore.ErrNoSuchEntity {
-				return nil, err
-			} else if err != nil && err == datastore.ErrNoSuchEntity {
+		p, ok := projects[key.ID]
+		if !ok {
+			p = new(Project)
+			if err := d.client.Get(d.ctx, d.root(key.ID), p); err == datastore.ErrNoSuchEntity {
 				return nil, ErrRecordNotFound
-			}
-			if err := json.Unmarshal([]byte(e.Data), &v); err != nil {
+			} else if err != nil {
 				return nil, err
 			}
-			cache[key.ID] = e
+			projects[key.ID] = p
 		}
-		p := d.Path(key)
-		if p == "version" {
-			store = append(store, v.Version)
-		} else {
-			for k, s := range v.Archive {
-				if p == k {
-					store = append(store, s)
-					break
-				}
-			}
+		path := d.Path(key)
+		if path == "version" {
+			vals = append(vals, p.Version)
+			continue
 		}
+		var r Record
+		if err := d.client.Get(d.ctx, d.record(key.ID, path), &r); err != nil && err != datastore.ErrNoSuchEntity {
+			return nil, err
+		}
+		vals = append(vals, r.Value)
 	}
-	return store, nil
+	return vals, nil
 }
 
 // List method
 func (d *GceDatastore) List(key *Key) ([]*Key, error) {
-	var e *Entity
-	var v *Versioning
-	ctx, client, err := d.storage()
-	if err != nil {
+	var p Project
+	if err := d.client.Get(d.ctx, d.root(key.ID), &p); err == datastore.ErrNoSuchEntity {
+		return nil, ErrRecordNotFound
+	} else if err != nil {
 		return nil, err
 	}
-	r := []*Key{}
-	k := datastore.NewKey(ctx, "Semver", key.ID, 0, nil)
-	if err := client.Get(ctx, k, &e); err != nil && err != datastore.ErrNoSuchEntity {
+	ks, err := d.records(key.ID, nil)
+	if err != nil {
 		return nil, err
-	} else if err != nil && err == datastore.ErrNoSuchEntity {
-		return nil, ErrRecordNotFound
 	}
-	if err := json.Unmarshal([]byte(e.Data), &v); err != nil {
-		return nil, err
+	path := d.Path(key)
+	keys := []*Key{}
+	if path == "" && p.Version != "" {
+		keys = append(keys, &Key{ID: key.ID, Dirs: []string{"version"}})
 	}
-	for k := range v.Archive {
-		s := strings.Split(k, ":")
-		r = append(r, &Key{ID: key.ID, Dirs: s})
+	for _, k := range ks {
+		if path != "" && !strings.HasPrefix(k.Name(), path+":") {
+			continue
+		}
+		keys = append(keys, &Key{ID: key.ID, Dirs: strings.Split(k.Name(), ":")})
 	}
-	return r, nil
+	return keys, nil
 }
 
-// Delete method
+// Delete method, a project entity is removed with its last record
 func (d *GceDatastore) Delete(keys ...*Key) error {
-	ctx, client, err := d.storage()
-	if err != nil {
-		return err
-	}
-	// entity cache
-	cache := make(map[string]*Entity)
-	for _, key := range keys {
-		var e *Entity
-		var v *Versioning
-		if o, ok := cache[key.ID]; ok {
-			e = o
-		} else {
-			k := datastore.NewKey(ctx, "Semver", key.ID, 0, nil)
-			if err := client.Get(ctx, k, &v); err != nil && err != datastore.ErrNoSuchEntity {
-				return err
-			} else if err != nil && err == datastore.ErrNoSuchEntity {
+	_, err := d.client.RunInTransaction(d.ctx, func(tx *datastore.Transaction) error {
+		paths := make(map[string]map[string]bool)
+		for _, key := range keys {
+			if paths[key.ID] == nil {
+				paths[key.ID] = make(map[string]bool)
+			}
+			paths[key.ID][d.Path(key)] = true
+		}
+		for id, deleted := range paths {
+			p := new(Project)
+			if err := tx.Get(d.root(id), p); err == datastore.ErrNoSuchEntity {
 				continue
+			} else if err != nil {
+				return err
+			}
+			ks, err := d.records(id, tx)
+			if err != nil {
+				return err
+			}
+			if deleted["version"] || deleted[""] {
+				p.Version = ""
 			}
-			if err := json.Unmarshal([]byte(e.Data), &v); err != nil {
+			remaining := 0
+			for _, k := range ks {
+				if deleted[""] || deleted[k.Name()] {
+					if err := tx.Delete(k); err != nil {
+						return err
+					}
+				} else {
+					remaining++
+				}
+			}
+			if remaining == 0 && p.Version == "" {
+				if err := tx.Delete(d.root(id)); err != nil {
+					return err
+				}
+			} else if _, err := tx.Put(d.root(id), p); err != nil {
 				return err
 			}
 		}
-		p := d.Path(key)
-		if p == "version" {
-			v.Version = ""
-		} else {
-			delete(v.Archive, p)
+		return nil
+	})
+	return err
+}
+
+// Apply method, checks and writes of every project run in one cross group
+// transaction
+func (d *GceDatastore) Apply(checks []*Check, ops []*Op) error {
+	_, err := d.client.RunInTransaction(d.ctx, func(tx *datastore.Transaction) error {
+		projects := make(map[string]*Project)
+		project := func(id string) (*Project, error) {
+			if p, ok := projects[id]; ok {
+				return p, nil
+			}
+			p := new(Project)
+			if err := tx.Get(d.root(id), p); err != nil && err != datastore.ErrNoSuchEntity {
+				return nil, err
+			}
+			projects[id] = p
+			return p, nil
 		}
-		b, err := json.Marshal(v)
-		if err != nil {
-			return err
+		for _, check := range checks {
+			p, err := project(check.Key.ID)
+			if err != nil {
+				return err
+			}
+			val := p.Version
+			if path := d.Path(check.Key); path != "version" {
+				var r Record
+				if err := tx.Get(d.record(check.Key.ID, path), &r); err != nil && err != datastore.ErrNoSuchEntity {
+					return err
+				}
+				val = r.Value
+			}
+			if val != check.Val {
+				return ErrConflict
+			}
 		}
-		e.Data = string(b[:])
-		cache[key.ID] = e
-	}
-	for i, e := range cache {
-		var v *Versioning
-		k := datastore.NewKey(ctx, "Semver", i, 0, nil)
-		if err := json.Unmarshal([]byte(e.Data), &v); err != nil {
-			return err
+		written := make(map[string]*Project)
+		for _, op := range ops {
+			p, err := project(op.Key.ID)
+			if err != nil {
+				return err
+			}
+			written[op.Key.ID] = p
+			if path := d.Path(op.Key); path == "version" {
+				p.Version = op.Val
+			} else if _, err := tx.Put(d.record(op.Key.ID, path), &Record{Value: op.Val}); err != nil {
+				return err
+			}
 		}
-		if len(v.Archive) > 0 {
-			if _, err := client.Put(ctx, k, e); err != nil {
+		for id, p := range written {
+			p.Updated = time.Now().UTC()
+			if _, err := tx.Put(d.root(id), p); err != nil {
 				return err
 			}
-		} else {
-			if err := client.Delete(ctx, k); err != nil {
+		}
+		return nil
+	})
+	if err == datastore.ErrConcurrentTransaction {
+		return ErrConflict
+	}
+	return err
+}
+
+// Incr method
+func (d *GceDatastore) Incr(key *Key, delta int64) (int64, error) {
+	var n int64
+	_, err := d.client.RunInTransaction(d.ctx, func(tx *datastore.Transaction) error {
+		p := new(Project)
+		if err := tx.Get(d.root(key.ID), p); err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+		k := d.record(key.ID, d.Path(key))
+		var r Record
+		if err := tx.Get(k, &r); err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+		n = 0
+		if r.Value != "" {
+			var err error
+			if n, err = strconv.ParseInt(r.Value, 10, 64); err != nil {
 				return err
 			}
 		}
+		n += delta
+		if _, err := tx.Put(k, &Record{Value: strconv.FormatInt(n, 10)}); err != nil {
+			return err
+		}
+		p.Updated = time.Now().UTC()
+		_, err := tx.Put(d.root(key.ID), p)
+		return err
+	})
+	if err == datastore.ErrConcurrentTransaction {
+		return 0, ErrConflict
+	}
+	return n, err
+}
+
+// lease returns the key of a lease in the project entity group
+func (d *GceDatastore) lease(id, path string) *datastore.Key {
+	return datastore.NewKey(d.ctx, leaseKind, path, 0, d.root(id))
+}
+
+// Lease method
+func (d *GceDatastore) Lease(key *Key, val string, ttl time.Duration) (bool, error) {
+	ok := false
+	_, err := d.client.RunInTransaction(d.ctx, func(tx *datastore.Transaction) error {
+		k := d.lease(key.ID, d.Path(key))
+		var l leaseEntity
+		if err := tx.Get(k, &l); err == nil && time.Now().Before(l.Expires) {
+			return nil
+		} else if err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+		ok = true
+		_, err := tx.Put(k, &leaseEntity{Value: val, Expires: time.Now().Add(ttl).UTC()})
+		return err
+	})
+	if err == datastore.ErrConcurrentTransaction {
+		return false, nil
+	}
+	return ok, err
+}
+
+// Leases method
+func (d *GceDatastore) Leases(key *Key) ([]*Lease, error) {
+	var ls []*leaseEntity
+	ks, err := d.client.GetAll(d.ctx, datastore.NewQuery(leaseKind).Ancestor(d.root(key.ID)), &ls)
+	if err != nil {
+		return nil, err
+	}
+	path := d.Path(key)
+	now := time.Now()
+	leases := []*Lease{}
+	for i, k := range ks {
+		if path != "" && !strings.HasPrefix(k.Name(), path+":") {
+			continue
+		}
+		if !now.Before(ls[i].Expires) {
+			continue
+		}
+		leases = append(leases, &Lease{
+			Key:     &Key{ID: key.ID, Dirs: strings.Split(k.Name(), ":")},
+			Val:     ls[i].Value,
+			Expires: ls[i].Expires,
+		})
+	}
+	return leases, nil
+}
+
+// Revoke method
+func (d *GceDatastore) Revoke(keys ...*Key) error {
+	for _, key := range keys {
+		if err := d.client.Delete(d.ctx, d.lease(key.ID, d.Path(key))); err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count method
+func (d *GceDatastore) Count() (int, error) {
+	return d.client.Count(d.ctx, datastore.NewQuery(projectKind).Filter("Version >", ""))
+}
+
+// Ping method
+func (d *GceDatastore) Ping() error {
+	var p Project
+	if err := d.client.Get(d.ctx, d.root("ping"), &p); err != nil && err != datastore.ErrNoSuchEntity {
+		return err
+	}
+	return nil
+}
+
+// Stats method
+func (d *GceDatastore) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"project":   d.project,
+		"namespace": env.Raw("SEMVER_DATASTORE_NAMESPACE"),
+		"emulator":  os.Getenv("DATASTORE_EMULATOR_HOST"),
+	}
+}
+
+// Close method
+func (d *GceDatastore) Close() error {
+	if d.client != nil {
+		d.client.Close()
 	}
 	return nil
 }
@@ -0,0 +1,62 @@
+package backend
+
+import (
+	"os"
+	"testing"
+
+	"google.golang.org/cloud/datastore"
+)
+
+// TestGceDatastore runs against the emulator at DATASTORE_EMULATOR_HOST in the
+// semver-test namespace. Count is not an ancestor query, start the emulator
+// with `gcloud beta emulators datastore start --consistency=1.0`.
+func TestGceDatastore(t *testing.T) {
+	if os.Getenv("DATASTORE_EMULATOR_HOST") == "" {
+		t.Skip("DATASTORE_EMULATOR_HOST is not set")
+	}
+	vars := map[string]string{"SEMVER_DATASTORE_NAMESPACE": "semver-test"}
+	if os.Getenv("DATASTORE_PROJECT_ID") == "" {
+		vars["SEMVER_BACKEND_DB"] = "semver-test"
+	}
+	defer setenv(vars)()
+	d := new(GceDatastore)
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	testClient(t, d)
+
+	// the version is a property of the project entity, other records are its
+	// child entities
+	id := project("layout")
+	defer d.Delete(&Key{ID: id})
+	if err := d.Set("1.0.0", &Key{ID: id, Dirs: []string{"version"}}, &Key{ID: id, Dirs: []string{"archive", "1.0.0"}}); err != nil {
+		t.Fatal(err)
+	}
+	var p Project
+	if err := d.client.Get(d.ctx, d.root(id), &p); err != nil || p.Version != "1.0.0" {
+		t.Errorf("project entity = %+v, %v", p, err)
+	}
+	var r Record
+	if err := d.client.Get(d.ctx, d.record(id, "archive:1.0.0"), &r); err != nil || r.Value != "1.0.0" {
+		t.Errorf("record entity = %+v, %v", r, err)
+	}
+	if err := d.client.Get(d.ctx, d.record(id, "version"), &r); err != datastore.ErrNoSuchEntity {
+		t.Errorf("record entity of the version = %v, want %v", err, datastore.ErrNoSuchEntity)
+	}
+
+	// projects stored as a single JSON blob are split into records
+	legacy := project("legacy")
+	defer d.Delete(&Key{ID: legacy})
+	data := `{"version": "2.0.0", "archive": {"archive:2.0.0": "2.0.0"}}`
+	if _, err := d.client.Put(d.ctx, d.root(legacy), &Project{Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.migrate(); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := d.Get(&Key{ID: legacy, Dirs: []string{"version"}}, &Key{ID: legacy, Dirs: []string{"archive", "2.0.0"}})
+	if err != nil || len(vals) != 2 || vals[0] != "2.0.0" || vals[1] != "2.0.0" {
+		t.Errorf("records of a migrated project = %v, %v", vals, err)
+	}
+}
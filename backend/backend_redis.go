@@ -1,133 +1,465 @@
 package backend
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"strings"
+	"time"
 
 	"gopkg.in/redis.v3"
 
 	"github.com/samuelngs/semver/pkg/env"
 )
 
-// Redis backend for semver
+// redisClient is implemented by standalone, sentinel and cluster clients
+type redisClient interface {
+	Exists(key string) *redis.BoolCmd
+	Del(keys ...string) *redis.IntCmd
+	HExists(key, field string) *redis.BoolCmd
+	HMSet(key, field, value string, pairs ...string) *redis.StatusCmd
+	HMGet(key string, fields ...string) *redis.SliceCmd
+	HKeys(key string) *redis.StringSliceCmd
+	HDel(key string, fields ...string) *redis.IntCmd
+	HIncrBy(key, field string, incr int64) *redis.IntCmd
+	SAdd(key string, members ...string) *redis.IntCmd
+	SRem(key string, members ...string) *redis.IntCmd
+	SCard(key string) *redis.IntCmd
+	SMembers(key string) *redis.StringSliceCmd
+	SetNX(key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	PTTL(key string) *redis.DurationCmd
+	Scan(cursor int64, match string, count int64) *redis.ScanCmd
+	Get(key string) *redis.StringCmd
+	Ping() *redis.StatusCmd
+	PoolStats() *redis.PoolStats
+	Close() error
+}
+
+// Redis backend for semver, every project is stored in one hash keyed by
+// `<prefix>{<id>}` so all records of a project share a cluster hash slot, the
+// set `<prefix>projects` indexes the project ids
 type Redis struct {
 	*Core
-	c *redis.Client
+	c      redisClient
+	prefix string
+	mode   string
 }
 
 // Init method
 func (r *Redis) Init() error {
-	opts := &redis.Options{
-		Addr:       env.Raw("SEMVER_BACKEND_ADDR", "localhost:6379"),
-		DB:         env.I64("SEMVER_BACKEND_DB", 0),
-		MaxRetries: env.Int("SEMVER_BACKEND_RETRIES", 5),
+	r.prefix = env.Raw("SEMVER_REDIS_PREFIX", "semver:")
+	r.mode = env.Raw("SEMVER_REDIS_MODE", "standalone")
+	addrs := strings.Split(env.Raw("SEMVER_BACKEND_ADDR", "localhost:6379"), ",")
+	password := env.Raw("SEMVER_BACKEND_PASSWORD")
+	retries := env.Int("SEMVER_BACKEND_RETRIES", 5)
+	if env.Bool("SEMVER_REDIS_TLS") && r.mode != "standalone" {
+		return fmt.Errorf("redis: TLS is only supported in standalone mode")
+	}
+	switch r.mode {
+	case "standalone":
+		opts := &redis.Options{
+			Addr:       addrs[0],
+			Password:   password,
+			DB:         env.I64("SEMVER_BACKEND_DB", 0),
+			MaxRetries: retries,
+		}
+		if env.Bool("SEMVER_REDIS_TLS") {
+			conf, err := r.tls(addrs[0])
+			if err != nil {
+				return err
+			}
+			opts.Dialer = func() (net.Conn, error) {
+				return tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", addrs[0], conf)
+			}
+		}
+		r.c = redis.NewClient(opts)
+	case "sentinel":
+		r.c = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    env.Raw("SEMVER_REDIS_MASTER", "mymaster"),
+			SentinelAddrs: addrs,
+			Password:      password,
+			DB:            env.I64("SEMVER_BACKEND_DB", 0),
+			MaxRetries:    retries,
+		})
+	case "cluster":
+		r.c = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        addrs,
+			Password:     password,
+			MaxRedirects: retries,
+		})
+	default:
+		return fmt.Errorf("redis: unsupported mode %q", r.mode)
+	}
+	if env.Bool("SEMVER_REDIS_MIGRATE") {
+		return r.migrate(password)
+	}
+	return nil
+}
+
+// tls creates the client tls configuration, only standalone mode accepts a
+// custom dialer in this redis client
+func (r *Redis) tls(addr string) (*tls.Config, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	conf := &tls.Config{ServerName: host}
+	if ca := env.Raw("SEMVER_REDIS_TLS_CA"); ca != "" {
+		pem, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = x509.NewCertPool()
+		if !conf.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis: %s does not contain any certificate", ca)
+		}
+	}
+	return conf, nil
+}
+
+// migrate moves records of the legacy `semver:db:<id>:<path>` string layout
+// into project hashes, it uses SCAN on every master in cluster mode and is
+// safe to run more than once
+func (r *Redis) migrate(password string) error {
+	c, ok := r.c.(*redis.ClusterClient)
+	if !ok {
+		return r.scan(r.c)
+	}
+	slots, err := c.ClusterSlots().Result()
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, slot := range slots {
+		if len(slot.Addrs) == 0 || seen[slot.Addrs[0]] {
+			continue
+		}
+		seen[slot.Addrs[0]] = true
+		node := redis.NewClient(&redis.Options{Addr: slot.Addrs[0], Password: password})
+		err := r.scan(node)
+		node.Close()
+		if err != nil {
+			return err
+		}
 	}
-	r.c = redis.NewClient(opts)
 	return nil
 }
 
+// scan migrates the legacy records found by SCAN on one node, the records are
+// read and deleted through the backend client
+func (r *Redis) scan(node redisClient) error {
+	var cursor int64
+	for {
+		next, keys, err := node.Scan(cursor, "semver:db:*", 1000).Result()
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			parts := strings.SplitN(strings.TrimPrefix(k, "semver:db:"), ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			val, err := r.c.Get(k).Result()
+			if err != nil {
+				continue
+			}
+			if err := r.Set(val, &Key{ID: parts[0], Dirs: strings.Split(parts[1], ":")}); err != nil {
+				return err
+			}
+			if err := r.c.Del(k).Err(); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
 // Name method
 func (r *Redis) Name() string {
 	return "redis"
 }
 
-// Path method
+// hash returns the project hash key
+func (r *Redis) hash(id string) string {
+	return r.prefix + "{" + id + "}"
+}
+
+// index returns the project index key
+func (r *Redis) index() string {
+	return r.prefix + "projects"
+}
+
+// Path method returns the hash field of a key
 func (r *Redis) Path(key *Key) string {
-	dir := fmt.Sprintf("semver:db:%s", key.ID)
-	for _, str := range key.Dirs {
-		dir += ":" + str
+	return strings.Join(key.Dirs, ":")
+}
+
+// group collects hash fields by project id
+func (r *Redis) group(keys []*Key) (map[string][]string, []string) {
+	fields := make(map[string][]string)
+	order := []string{}
+	for _, key := range keys {
+		if _, ok := fields[key.ID]; !ok {
+			order = append(order, key.ID)
+		}
+		fields[key.ID] = append(fields[key.ID], r.Path(key))
 	}
-	return dir
+	return fields, order
 }
 
 // Exists method
 func (r *Redis) Exists(key *Key) (bool, error) {
-	_, err := r.c.Get(r.Path(key)).Result()
-	if err == redis.Nil {
-		return false, nil
-	} else if err != nil {
-		return false, err
+	if len(key.Dirs) == 0 {
+		return r.c.Exists(r.hash(key.ID)).Result()
 	}
-	return true, nil
+	return r.c.HExists(r.hash(key.ID), r.Path(key)).Result()
 }
 
 // Set method
 func (r *Redis) Set(val string, keys ...*Key) error {
-	items := make([]string, len(keys)*2)
-	for i, key := range keys {
-		id := r.Path(key)
-		items[i*2] = id
-		items[i*2+1] = val
+	// HMSET needs at least one field
+	if len(keys) == 0 {
+		return nil
 	}
-	if err := r.c.MSet(items...).Err(); err != nil {
-		return err
+	fields, order := r.group(keys)
+	for _, id := range order {
+		pairs := make([]string, 0, len(fields[id])*2)
+		for _, f := range fields[id] {
+			pairs = append(pairs, f, val)
+		}
+		if err := r.c.HMSet(r.hash(id), pairs[0], pairs[1], pairs[2:]...).Err(); err != nil {
+			return err
+		}
+		for _, f := range fields[id] {
+			if f == "version" {
+				if err := r.c.SAdd(r.index(), id).Err(); err != nil {
+					return err
+				}
+				break
+			}
+		}
 	}
 	return nil
 }
 
 // Get method
 func (r *Redis) Get(keys ...*Key) ([]string, error) {
-	dirs := make([]string, len(keys))
-	for i, key := range keys {
-		dirs[i] = r.Path(key)
-	}
-	vs, err := r.c.MGet(dirs...).Result()
-	res := make([]string, len(vs))
-	for i, s := range vs {
-		res[i] = s.(string)
+	res := make([]string, 0, len(keys))
+	for _, key := range keys {
+		vs, err := r.c.HMGet(r.hash(key.ID), r.Path(key)).Result()
+		if err != nil {
+			return nil, err
+		}
+		var s string
+		if len(vs) > 0 {
+			s, _ = vs[0].(string)
+		}
+		res = append(res, s)
 	}
+	return res, nil
+}
+
+// List method, the cost is bound by the number of records of the project
+func (r *Redis) List(key *Key) ([]*Key, error) {
+	fields, err := r.c.HKeys(r.hash(key.ID)).Result()
 	if err != nil {
 		return nil, err
 	}
-	return res, nil
+	path := r.Path(key)
+	keys := []*Key{}
+	for _, f := range fields {
+		if path != "" && !strings.HasPrefix(f, path+":") {
+			continue
+		}
+		keys = append(keys, &Key{ID: key.ID, Dirs: strings.Split(f, ":")})
+	}
+	return keys, nil
 }
 
-// List method
-func (r *Redis) List(key *Key) ([]*Key, error) {
-	temp := &Key{ID: key.ID, Dirs: make([]string, len(key.Dirs))}
-	for i, v := range key.Dirs {
-		temp.Dirs[i] = v
+// Delete method
+func (r *Redis) Delete(keys ...*Key) error {
+	fields, order := r.group(keys)
+	for _, id := range order {
+		whole := false
+		for _, f := range fields[id] {
+			if f == "" {
+				whole = true
+			}
+		}
+		if whole {
+			if err := r.c.Del(r.hash(id)).Err(); err != nil {
+				return err
+			}
+		} else if err := r.c.HDel(r.hash(id), fields[id]...).Err(); err != nil {
+			return err
+		}
+		exists, err := r.c.HExists(r.hash(id), "version").Result()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if err := r.c.SRem(r.index(), id).Err(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Apply method, the project hashes are watched while the checks are read and
+// the writes are queued in MULTI/EXEC. Cluster mode is not supported as the
+// projects of a batch live in different hash slots.
+func (r *Redis) Apply(checks []*Check, ops []*Op) error {
+	c, ok := r.c.(*redis.Client)
+	if !ok {
+		return ErrNotSupported
 	}
-	if len(temp.Dirs) > 0 {
-		if s := temp.Dirs[len(temp.Dirs)-1]; !strings.Contains(s, "*") {
-			temp.Dirs = append(temp.Dirs, "*")
+	watched := []string{}
+	seen := make(map[string]bool)
+	for _, check := range checks {
+		if h := r.hash(check.Key.ID); !seen[h] {
+			seen[h] = true
+			watched = append(watched, h)
 		}
-	} else {
-		temp.Dirs = append(temp.Dirs, "*")
 	}
-	tar := strings.TrimSuffix(r.Path(temp), ":*")
-	ids, err := r.c.Keys(r.Path(temp)).Result()
+	if len(watched) == 0 {
+		watched = append(watched, r.index())
+	}
+	tx, err := c.Watch(watched...)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	keys := make([]*Key, len(ids))
-	for i, id := range ids {
-		pfx := strings.Replace(id, tar, "", -1)
-		str := strings.TrimPrefix(pfx, ":")
-		parts := strings.SplitAfter(str, ":")
-		dirs := make([]string, len(key.Dirs)+len(parts))
-		for i, v := range key.Dirs {
-			dirs[i] = v
+	defer tx.Close()
+	for _, check := range checks {
+		vs, err := tx.HMGet(r.hash(check.Key.ID), r.Path(check.Key)).Result()
+		if err != nil {
+			return err
 		}
-		for i, v := range parts {
-			dirs[i+len(key.Dirs)] = v
+		var val string
+		if len(vs) > 0 {
+			val, _ = vs[0].(string)
 		}
-		keys[i] = &Key{
-			ID:   key.ID,
-			Dirs: dirs,
+		if val != check.Val {
+			return ErrConflict
 		}
 	}
-	return keys, nil
+	_, err = tx.Exec(func() error {
+		for _, op := range ops {
+			tx.HMSet(r.hash(op.Key.ID), r.Path(op.Key), op.Val)
+			if r.Path(op.Key) == "version" {
+				tx.SAdd(r.index(), op.Key.ID)
+			}
+		}
+		return nil
+	})
+	if err == redis.TxFailedErr {
+		return ErrConflict
+	}
+	return err
 }
 
-// Delete method
-func (r *Redis) Delete(keys ...*Key) error {
-	ids := make([]string, len(keys))
-	for i, v := range keys {
-		ids[i] = r.Path(v)
+// Incr method, HINCRBY is atomic in every mode
+func (r *Redis) Incr(key *Key, delta int64) (int64, error) {
+	return r.c.HIncrBy(r.hash(key.ID), r.Path(key), delta).Result()
+}
+
+// lease returns the key of a lease, leases are string keys expiring natively
+// next to the project hash, the set `<prefix>{<id>}:leases` indexes them
+func (r *Redis) lease(key *Key) string {
+	return r.hash(key.ID) + ":leases:" + r.Path(key)
+}
+
+// Lease method
+func (r *Redis) Lease(key *Key, val string, ttl time.Duration) (bool, error) {
+	ok, err := r.c.SetNX(r.lease(key), val, ttl).Result()
+	if err != nil || !ok {
+		return false, err
 	}
-	if _, err := r.c.Del(ids...).Result(); err != nil {
-		return err
+	return true, r.c.SAdd(r.hash(key.ID)+":leases", r.Path(key)).Err()
+}
+
+// Leases method, expired leases are removed from the index as they are found
+func (r *Redis) Leases(key *Key) ([]*Lease, error) {
+	paths, err := r.c.SMembers(r.hash(key.ID) + ":leases").Result()
+	if err != nil {
+		return nil, err
+	}
+	prefix := r.Path(key)
+	if prefix != "" {
+		prefix += ":"
+	}
+	leases := []*Lease{}
+	for _, path := range paths {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		k := &Key{ID: key.ID, Dirs: strings.Split(path, ":")}
+		val, err := r.c.Get(r.lease(k)).Result()
+		if err == redis.Nil {
+			if err := r.c.SRem(r.hash(key.ID)+":leases", path).Err(); err != nil {
+				return nil, err
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		ttl, err := r.c.PTTL(r.lease(k)).Result()
+		if err != nil {
+			return nil, err
+		}
+		leases = append(leases, &Lease{Key: k, Val: val, Expires: time.Now().Add(ttl)})
+	}
+	return leases, nil
+}
+
+// Revoke method
+func (r *Redis) Revoke(keys ...*Key) error {
+	for _, key := range keys {
+		if err := r.c.Del(r.lease(key)).Err(); err != nil {
+			return err
+		}
+		if err := r.c.SRem(r.hash(key.ID)+":leases", r.Path(key)).Err(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+// Count method
+func (r *Redis) Count() (int, error) {
+	n, err := r.c.SCard(r.index()).Result()
+	return int(n), err
+}
+
+// Ping method
+func (r *Redis) Ping() error {
+	return r.c.Ping().Err()
+}
+
+// Stats method
+func (r *Redis) Stats() map[string]interface{} {
+	stats := r.c.PoolStats()
+	return map[string]interface{}{
+		"mode":        r.mode,
+		"prefix":      r.prefix,
+		"requests":    stats.Requests,
+		"hits":        stats.Hits,
+		"waits":       stats.Waits,
+		"timeouts":    stats.Timeouts,
+		"total_conns": stats.TotalConns,
+		"free_conns":  stats.FreeConns,
+	}
+}
+
+// Close method
+func (r *Redis) Close() error {
+	if r.c == nil {
+		return nil
+	}
+	return r.c.Close()
+}
@@ -0,0 +1,62 @@
+package backend
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/redis.v3"
+)
+
+// TestRedis runs against a disposable server at SEMVER_TEST_REDIS_ADDR, e.g.
+// `docker run -p 6379:6379 redis`, legacy keys found on it are migrated into
+// the semver-test: prefix
+func TestRedis(t *testing.T) {
+	addr := os.Getenv("SEMVER_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("SEMVER_TEST_REDIS_ADDR is not set")
+	}
+	defer setenv(map[string]string{
+		"SEMVER_BACKEND_ADDR": addr,
+		"SEMVER_REDIS_PREFIX": "semver-test:",
+	})()
+	r := new(Redis)
+	if err := r.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	testClient(t, r)
+	if r.mode != "standalone" {
+		return
+	}
+
+	// records of the legacy `semver:db:<id>:<path>` layout move into the
+	// project hash and the project index
+	id := project("legacy")
+	legacy := redis.NewClient(&redis.Options{Addr: strings.Split(addr, ",")[0]})
+	defer legacy.Close()
+	for path, val := range map[string]string{"version": "1.0.0", "archive:1.0.0": "1.0.0"} {
+		if err := legacy.Set("semver:db:"+id+":"+path, val, 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer r.Delete(&Key{ID: id})
+	if err := r.migrate(""); err != nil {
+		t.Fatal(err)
+	}
+	fields, err := legacy.HKeys("semver-test:{" + id + "}").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(fields)
+	if strings.Join(fields, " ") != "archive:1.0.0 version" {
+		t.Errorf("fields of the project hash = %v", fields)
+	}
+	if ok, err := legacy.SIsMember("semver-test:projects", id).Result(); err != nil || !ok {
+		t.Errorf("project index contains the migrated project = %v, %v", ok, err)
+	}
+	if exists, err := legacy.Exists("semver:db:" + id + ":version").Result(); err != nil || exists {
+		t.Errorf("legacy record exists after the migration = %v, %v", exists, err)
+	}
+}
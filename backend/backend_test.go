@@ -0,0 +1,283 @@
+package backend
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setenv sets environment variables and returns a function restoring them
+func setenv(vars map[string]string) func() {
+	prev := make(map[string]string, len(vars))
+	for k, v := range vars {
+		prev[k] = os.Getenv(k)
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k, v := range prev {
+			if v == "" {
+				os.Unsetenv(k)
+			} else {
+				os.Setenv(k, v)
+			}
+		}
+	}
+}
+
+// project returns an id no other test run uses, the backends of the gated
+// tests may be shared
+func project(name string) string {
+	return "test-" + name + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// paths returns the sorted paths of keys
+func paths(keys []*Key) []string {
+	s := make([]string, len(keys))
+	for i, k := range keys {
+		s[i] = strings.Join(k.Dirs, "/")
+	}
+	sort.Strings(s)
+	return s
+}
+
+// value reads one record, backends differ in the order and the placeholders
+// of batched reads
+func value(t *testing.T, c Client, k *Key) string {
+	t.Helper()
+	vals, err := c.Get(k)
+	if err != nil {
+		t.Fatalf("Get(%v) = %v", k.Dirs, err)
+	}
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
+
+// testClient runs the behaviour every backend client shares against c
+func testClient(t *testing.T, c Client) {
+	id, other := project("a"), project("b")
+	key := func(id string, dirs ...string) *Key {
+		return &Key{ID: id, Dirs: dirs}
+	}
+	defer func() {
+		for _, p := range []string{id, other} {
+			if keys, err := c.List(key(p)); err == nil && len(keys) > 0 {
+				c.Delete(keys...)
+			}
+			if e, ok := c.(Expirer); ok {
+				if leases, err := e.Leases(key(p)); err == nil {
+					for _, l := range leases {
+						e.Revoke(l.Key)
+					}
+				}
+			}
+		}
+	}()
+
+	count, err := c.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := c.Exists(key(id)); err != nil || ok {
+		t.Fatalf("Exists before Set = %v, %v", ok, err)
+	}
+	if err := c.Set("1.0.0", key(id, "version"), key(id, "archive", "1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []*Key{
+		key(id, "components", "api", "version"),
+		key(id, "components", "api", "archive", "1.4.0"),
+		key(id, "components", "api2", "version"),
+	} {
+		if err := c.Set("1.4.0", k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ok, err := c.Exists(key(id)); err != nil || !ok {
+		t.Errorf("Exists after Set = %v, %v", ok, err)
+	}
+	if got := value(t, c, key(id, "version")); got != "1.0.0" {
+		t.Errorf("Get(version) = %q, want 1.0.0", got)
+	}
+	if got := value(t, c, key(id, "components", "api", "version")); got != "1.4.0" {
+		t.Errorf("Get(components/api/version) = %q, want 1.4.0", got)
+	}
+	if n, err := c.Count(); err != nil || n != count+1 {
+		t.Errorf("Count = %d, %v, want %d", n, err, count+1)
+	}
+
+	// a listing returns the records under a path, not the siblings sharing
+	// its name prefix
+	lists := []struct {
+		dirs []string
+		want []string
+	}{
+		{nil, []string{"archive/1.0.0", "components/api/archive/1.4.0", "components/api/version", "components/api2/version", "version"}},
+		{[]string{"components"}, []string{"components/api/archive/1.4.0", "components/api/version", "components/api2/version"}},
+		{[]string{"components", "api"}, []string{"components/api/archive/1.4.0", "components/api/version"}},
+		{[]string{"components", "api", "archive"}, []string{"components/api/archive/1.4.0"}},
+		{[]string{"tags"}, []string{}},
+	}
+	for _, tt := range lists {
+		keys, err := c.List(key(id, tt.dirs...))
+		if err != nil {
+			t.Errorf("List(%v) = %v", tt.dirs, err)
+		} else if got := paths(keys); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("List(%v) = %v, want %v", tt.dirs, got, tt.want)
+		}
+	}
+	if err := c.Delete(key(id, "components", "api2", "version")); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := c.List(key(id, "components")); err != nil || len(keys) != 2 {
+		t.Errorf("List(components) after Delete = %v, %v", paths(keys), err)
+	}
+
+	if tx, ok := c.(Transactional); ok {
+		testApply(t, c, tx, id, other)
+	}
+	if ctr, ok := c.(Counter); ok {
+		for i, want := range []int64{1, 2, 12, 11} {
+			delta := []int64{1, 1, 10, -1}[i]
+			if n, err := ctr.Incr(key(id, "counters", "build", "value"), delta); err != nil || n != want {
+				t.Errorf("Incr(%d) = %d, %v, want %d", delta, n, err, want)
+			}
+		}
+		if got := value(t, c, key(id, "counters", "build", "value")); got != "11" {
+			t.Errorf("Get of a counter = %q, want 11", got)
+		}
+	}
+	if e, ok := c.(Expirer); ok {
+		testLeases(t, e, id)
+	}
+
+	// deleting every record removes the project
+	keys, err := c.List(key(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(keys...); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := c.Exists(key(id)); err != nil || ok {
+		t.Errorf("Exists after Delete = %v, %v", ok, err)
+	}
+	if n, err := c.Count(); err != nil || n != count {
+		t.Errorf("Count after Delete = %d, %v, want %d", n, err, count)
+	}
+}
+
+// testApply checks that a transaction is written only when its checks hold
+func testApply(t *testing.T, c Client, tx Transactional, id, other string) {
+	version := &Key{ID: id, Dirs: []string{"version"}}
+	archive := &Key{ID: id, Dirs: []string{"archive", "1.1.0"}}
+
+	tests := []struct {
+		name   string
+		checks []*Check
+		want   error
+	}{
+		{"stale version", []*Check{{Key: version, Val: "0.9.0"}}, ErrConflict},
+		{"existing record expected missing", []*Check{{Key: version, Val: "1.0.0"}, {Key: &Key{ID: id, Dirs: []string{"archive", "1.0.0"}}}}, ErrConflict},
+		{"checks hold", []*Check{{Key: version, Val: "1.0.0"}, {Key: archive}}, nil},
+		{"replayed", []*Check{{Key: version, Val: "1.0.0"}, {Key: archive}}, ErrConflict},
+	}
+	ops := []*Op{{Key: version, Val: "1.1.0"}, {Key: archive, Val: "1.1.0"}}
+	for _, tt := range tests {
+		if err := tx.Apply(tt.checks, ops); err != tt.want {
+			t.Errorf("Apply(%s) = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if got := value(t, c, version); got != "1.1.0" {
+		t.Errorf("version after Apply = %q, want 1.1.0", got)
+	}
+	if got := value(t, c, archive); got != "1.1.0" {
+		t.Errorf("archive after Apply = %q, want 1.1.0", got)
+	}
+
+	// a transaction of several projects is applied entirely or rejected by
+	// backends that cannot span projects
+	err := tx.Apply(
+		[]*Check{{Key: version, Val: "1.1.0"}, {Key: &Key{ID: other, Dirs: []string{"version"}}}},
+		[]*Op{{Key: version, Val: "1.2.0"}, {Key: &Key{ID: other, Dirs: []string{"version"}}, Val: "0.1.0"}},
+	)
+	switch err {
+	case ErrNotSupported:
+		if got := value(t, c, version); got != "1.1.0" {
+			t.Errorf("version after a rejected Apply = %q, want 1.1.0", got)
+		}
+	case nil:
+		if got := value(t, c, version); got != "1.2.0" {
+			t.Errorf("version after Apply = %q, want 1.2.0", got)
+		}
+		if got := value(t, c, &Key{ID: other, Dirs: []string{"version"}}); got != "0.1.0" {
+			t.Errorf("version of another project after Apply = %q, want 0.1.0", got)
+		}
+		if err := c.Delete(&Key{ID: other, Dirs: []string{"version"}}); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Errorf("Apply of two projects = %v", err)
+	}
+}
+
+// testLeases checks that a lease is held until it expires or is revoked
+func testLeases(t *testing.T, e Expirer, id string) {
+	lease := func(name string, ttl time.Duration) bool {
+		t.Helper()
+		ok, err := e.Lease(&Key{ID: id, Dirs: []string{"reservations", name}}, "token-"+name, ttl)
+		if err != nil {
+			t.Fatalf("Lease(%s) = %v", name, err)
+		}
+		return ok
+	}
+	leases := func(dirs ...string) []string {
+		t.Helper()
+		ls, err := e.Leases(&Key{ID: id, Dirs: dirs})
+		if err != nil {
+			t.Fatalf("Leases(%v) = %v", dirs, err)
+		}
+		s := make([]string, len(ls))
+		for i, l := range ls {
+			s[i] = strings.Join(l.Key.Dirs, "/") + "=" + l.Val
+			if !l.Expires.After(time.Now()) {
+				t.Errorf("lease %s expired at %v", s[i], l.Expires)
+			}
+		}
+		sort.Strings(s)
+		return s
+	}
+
+	if !lease("1.5.0", time.Minute) || !lease("1.6.0", time.Minute) || !lease("1.7.0", time.Second) {
+		t.Fatal("Lease of a free key = false")
+	}
+	if lease("1.5.0", time.Minute) {
+		t.Error("Lease of a leased key = true")
+	}
+	want := []string{"reservations/1.5.0=token-1.5.0", "reservations/1.6.0=token-1.6.0", "reservations/1.7.0=token-1.7.0"}
+	if got := leases("reservations"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Leases = %v, want %v", got, want)
+	}
+	if got := leases("reserv"); len(got) != 0 {
+		t.Errorf("Leases of a name prefix = %v", got)
+	}
+	if got := leases(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Leases of the project = %v, want %v", got, want)
+	}
+	if err := e.Revoke(&Key{ID: id, Dirs: []string{"reservations", "1.6.0"}}); err != nil {
+		t.Fatal(err)
+	}
+	// ttls of some backends are whole seconds
+	time.Sleep(2 * time.Second)
+	if got, want := leases("reservations"), want[:1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Leases after Revoke and expiry = %v, want %v", got, want)
+	}
+	if !lease("1.6.0", time.Minute) || !lease("1.7.0", time.Minute) {
+		t.Error("Lease of a revoked or expired key = false")
+	}
+}
@@ -5,4 +5,6 @@ import "errors"
 // List of error messages
 var (
 	ErrRecordNotFound = errors.New("does not match any records in our database")
+	ErrNotSupported   = errors.New("operation is not supported by the storage backend")
+	ErrConflict       = errors.New("records were modified by another request")
 )
@@ -1,5 +1,10 @@
 package backend
 
+import (
+	"io"
+	"time"
+)
+
 // Client interface
 type Client interface {
 	Init() error
@@ -10,6 +15,39 @@ type Client interface {
 	Get(keys ...*Key) ([]string, error)
 	List(key *Key) ([]*Key, error)
 	Delete(keys ...*Key) error
+	Count() (int, error)
+	Ping() error
+	Stats() map[string]interface{}
+	Close() error
+}
+
+// Backuper is implemented by clients that can stream a consistent copy of
+// their database
+type Backuper interface {
+	Backup(w io.Writer) (int64, error)
+}
+
+// Transactional is implemented by clients that can apply writes to several
+// projects atomically, the writes are only applied when every check holds
+type Transactional interface {
+	Apply(checks []*Check, ops []*Op) error
+}
+
+// Counter is implemented by clients that can increase a number record
+// atomically, a missing record counts from 0
+type Counter interface {
+	Incr(key *Key, delta int64) (int64, error)
+}
+
+// Expirer is implemented by clients that can store leases, records expiring
+// after a ttl. Expired leases are never returned.
+type Expirer interface {
+	// Lease creates the lease of key unless an unexpired lease exists
+	Lease(key *Key, val string, ttl time.Duration) (bool, error)
+	// Leases lists the unexpired leases under key
+	Leases(key *Key) ([]*Lease, error)
+	// Revoke removes leases
+	Revoke(keys ...*Key) error
 }
 
 // Core for extend purpose
@@ -54,3 +92,23 @@ func (e *Core) List(key *Key) ([]*Key, error) {
 func (e *Core) Delete(keys ...*Key) error {
 	panic("you should override `delete` method")
 }
+
+// Count method
+func (e *Core) Count() (int, error) {
+	panic("you should override `count` method")
+}
+
+// Ping method
+func (e *Core) Ping() error {
+	panic("you should override `ping` method")
+}
+
+// Stats method
+func (e *Core) Stats() map[string]interface{} {
+	panic("you should override `stats` method")
+}
+
+// Close method
+func (e *Core) Close() error {
+	panic("you should override `close` method")
+}
@@ -1,5 +1,28 @@
 package backend
 
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/samuelngs/semver/pkg/metrics"
+)
+
+// backend operation metrics
+var (
+	opDuration = metrics.Default.NewHistogramVec(
+		"semver_backend_operation_duration_seconds",
+		"Latency of backend operations.",
+		nil,
+		"backend", "operation",
+	)
+	opErrors = metrics.Default.NewCounterVec(
+		"semver_backend_operation_errors_total",
+		"Number of failed backend operations.",
+		"backend", "operation",
+	)
+)
+
 // New creates backend manager
 func New(opts ...Client) *Manager {
 	var c Client
@@ -17,12 +40,47 @@ func New(opts ...Client) *Manager {
 // Manager represent the backend manager
 type Manager struct {
 	c Client
+
+	mu      sync.RWMutex
+	initErr error
+	lastErr error
+	lastAt  time.Time
 }
 
-// Use to import backend client
-func (m *Manager) Use(c Client) {
+// Use to import backend client, the initialization error is kept and
+// returned by Err and by every storage operation
+func (m *Manager) Use(c Client) error {
 	m.c = c
-	m.c.Init()
+	err := m.c.Init()
+	m.mu.Lock()
+	m.initErr = err
+	m.mu.Unlock()
+	if err != nil {
+		m.fail(err)
+	}
+	return err
+}
+
+// Err returns the initialization error of the backend client
+func (m *Manager) Err() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.initErr
+}
+
+// LastError returns the last backend error and the time it occurred
+func (m *Manager) LastError() (error, time.Time) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastErr, m.lastAt
+}
+
+// fail keeps the last backend error
+func (m *Manager) fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastErr = err
+	m.lastAt = time.Now().UTC()
 }
 
 // before action
@@ -44,32 +102,210 @@ func (m *Manager) Path(id string, dirs ...string) *Key {
 	return &Key{ID: id, Dirs: dirs}
 }
 
+// observe records latency and error of a backend operation
+func (m *Manager) observe(op string, start time.Time, err error) {
+	name := m.c.Name()
+	opDuration.Observe(time.Since(start).Seconds(), name, op)
+	if err != nil && err != ErrRecordNotFound && err != ErrConflict {
+		opErrors.Inc(name, op)
+		m.fail(err)
+	}
+}
+
 // Exists checker
 func (m *Manager) Exists(key *Key) (bool, error) {
 	m.prepare()
-	return m.c.Exists(key)
+	if err := m.Err(); err != nil {
+		return false, err
+	}
+	start := time.Now()
+	exists, err := m.c.Exists(key)
+	m.observe("exists", start, err)
+	return exists, err
 }
 
 // Set data to storage
 func (m *Manager) Set(val string, keys ...*Key) error {
 	m.prepare()
-	return m.c.Set(val, keys...)
+	if err := m.Err(); err != nil {
+		return err
+	}
+	start := time.Now()
+	err := m.c.Set(val, keys...)
+	m.observe("set", start, err)
+	return err
 }
 
 // Get method
 func (m *Manager) Get(keys ...*Key) ([]string, error) {
 	m.prepare()
-	return m.c.Get(keys...)
+	if err := m.Err(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	vals, err := m.c.Get(keys...)
+	m.observe("get", start, err)
+	return vals, err
 }
 
 // List method
 func (m *Manager) List(key *Key) ([]*Key, error) {
 	m.prepare()
-	return m.c.List(key)
+	if err := m.Err(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	keys, err := m.c.List(key)
+	m.observe("list", start, err)
+	return keys, err
 }
 
 // Delete method
 func (m *Manager) Delete(keys ...*Key) error {
 	m.prepare()
-	return m.c.Delete(keys...)
+	if err := m.Err(); err != nil {
+		return err
+	}
+	start := time.Now()
+	err := m.c.Delete(keys...)
+	m.observe("delete", start, err)
+	return err
+}
+
+// Count returns the number of projects
+func (m *Manager) Count() (int, error) {
+	m.prepare()
+	if err := m.Err(); err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	count, err := m.c.Count()
+	m.observe("count", start, err)
+	return count, err
+}
+
+// Ping performs a round-trip to the storage backend
+func (m *Manager) Ping() error {
+	m.prepare()
+	if err := m.Err(); err != nil {
+		return err
+	}
+	start := time.Now()
+	err := m.c.Ping()
+	m.observe("ping", start, err)
+	return err
+}
+
+// Stats returns connection statistics of the storage backend
+func (m *Manager) Stats() map[string]interface{} {
+	m.prepare()
+	if m.Err() != nil {
+		return map[string]interface{}{}
+	}
+	return m.c.Stats()
+}
+
+// Backup streams a consistent copy of the storage backend database
+func (m *Manager) Backup(w io.Writer) (int64, error) {
+	m.prepare()
+	if err := m.Err(); err != nil {
+		return 0, err
+	}
+	b, ok := m.c.(Backuper)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	start := time.Now()
+	n, err := b.Backup(w)
+	m.observe("backup", start, err)
+	return n, err
+}
+
+// Apply writes ops in one transaction when every check holds, backends
+// without transactions, e.g. redis in cluster mode, return ErrNotSupported
+func (m *Manager) Apply(checks []*Check, ops []*Op) error {
+	m.prepare()
+	if err := m.Err(); err != nil {
+		return err
+	}
+	t, ok := m.c.(Transactional)
+	if !ok {
+		return ErrNotSupported
+	}
+	start := time.Now()
+	err := t.Apply(checks, ops)
+	m.observe("apply", start, err)
+	return err
+}
+
+// Incr increases the number record of key by delta and returns the new value
+func (m *Manager) Incr(key *Key, delta int64) (int64, error) {
+	m.prepare()
+	if err := m.Err(); err != nil {
+		return 0, err
+	}
+	c, ok := m.c.(Counter)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	start := time.Now()
+	n, err := c.Incr(key, delta)
+	m.observe("incr", start, err)
+	return n, err
+}
+
+// expirer returns the lease capability of the client
+func (m *Manager) expirer() (Expirer, error) {
+	m.prepare()
+	if err := m.Err(); err != nil {
+		return nil, err
+	}
+	e, ok := m.c.(Expirer)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return e, nil
+}
+
+// Lease creates the lease of key for ttl, it reports false when the key is
+// already leased
+func (m *Manager) Lease(key *Key, val string, ttl time.Duration) (bool, error) {
+	e, err := m.expirer()
+	if err != nil {
+		return false, err
+	}
+	start := time.Now()
+	ok, err := e.Lease(key, val, ttl)
+	m.observe("lease", start, err)
+	return ok, err
+}
+
+// Leases lists the unexpired leases under key
+func (m *Manager) Leases(key *Key) ([]*Lease, error) {
+	e, err := m.expirer()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	leases, err := e.Leases(key)
+	m.observe("leases", start, err)
+	return leases, err
+}
+
+// Revoke removes leases
+func (m *Manager) Revoke(keys ...*Key) error {
+	e, err := m.expirer()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	err = e.Revoke(keys...)
+	m.observe("revoke", start, err)
+	return err
+}
+
+// Close releases the storage backend connections
+func (m *Manager) Close() error {
+	m.prepare()
+	return m.c.Close()
 }
@@ -1,18 +1,41 @@
 package backend
 
+import "time"
+
 // Key struct
 type Key struct {
 	ID   string
 	Dirs []string
 }
 
-// Entity represents a record
-type Entity struct {
-	Data string `json:"data"`
+// Check is a precondition of a transaction, the record of Key must hold Val,
+// an empty Val expects a missing record
+type Check struct {
+	Key *Key
+	Val string
+}
+
+// Op is a write of a transaction
+type Op struct {
+	Key *Key
+	Val string
+}
+
+// Lease is a record expiring at Expires
+type Lease struct {
+	Key     *Key
+	Val     string
+	Expires time.Time
+}
+
+// Project represents the root entity of a project, it holds the version pointer
+type Project struct {
+	Version string
+	Updated time.Time
+	Data    string `datastore:",noindex"` // legacy JSON blob, emptied by the migration
 }
 
-// Versioning represents a version record
-type Versioning struct {
-	Version string            `json:"version"`
-	Archive map[string]string `json:"archive"`
+// Record represents a child entity of a project, e.g. an archived version
+type Record struct {
+	Value string `datastore:",noindex"`
 }
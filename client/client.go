@@ -0,0 +1,273 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/samuelngs/semver/pkg/api"
+)
+
+// Bump types
+const (
+	Major = "major"
+	Minor = "minor"
+	Patch = "patch"
+)
+
+// Client is a client of the semver api
+type Client struct {
+	// BaseURL of the server, e.g. https://semver.co
+	BaseURL string
+	// Token is sent as a bearer token, it is required for administrator operations
+	Token string
+	// HTTPClient used for requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// Retries of reads failing with a network error or a 5xx status, writes
+	// are only retried when the request could not be sent
+	Retries int
+	// Backoff before the first retry, doubled on each retry
+	Backoff time.Duration
+}
+
+// New creates a client with 3 retries and a 200ms initial backoff
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		Retries: 3,
+		Backoff: 200 * time.Millisecond,
+	}
+}
+
+// Create creates a project, version defaults to 0.0.1 on the server when empty
+func (c *Client) Create(ctx context.Context, version string) (*api.Versioning, error) {
+	return c.CreateScheme(ctx, version, "", "")
+}
+
+// CreateScheme creates a project with a versioning scheme {semver, calver,
+// build}, format is the calver format, e.g. YYYY.0M.MICRO
+func (c *Client) CreateScheme(ctx context.Context, version, scheme, format string) (*api.Versioning, error) {
+	q := url.Values{}
+	if version != "" {
+		q.Set("version", version)
+	}
+	if scheme != "" {
+		q.Set("scheme", scheme)
+	}
+	if format != "" {
+		q.Set("format", format)
+	}
+	res := new(api.Versioning)
+	return res, c.write(ctx, "GET", "/v1/new", q, nil, res)
+}
+
+// Get returns the current version of project `id`
+func (c *Client) Get(ctx context.Context, id string) (*api.Versioning, error) {
+	res := new(api.Versioning)
+	return res, c.read(ctx, "/v1/"+url.PathEscape(id), nil, res)
+}
+
+// Set sets the version of project `id`
+func (c *Client) Set(ctx context.Context, id, version string) (*api.Versioning, error) {
+	form := url.Values{"version": {version}}
+	res := new(api.Versioning)
+	return res, c.write(ctx, "POST", "/v1/"+url.PathEscape(id), nil, form, res)
+}
+
+// Bump increases the version of project `id` by type {major, minor, patch}
+func (c *Client) Bump(ctx context.Context, id, typ string) (*api.Versioning, error) {
+	q := url.Values{"type": {typ}}
+	res := new(api.Versioning)
+	return res, c.write(ctx, "GET", "/v1/"+url.PathEscape(id)+"/bump", q, nil, res)
+}
+
+// AutoBump bumps the version of project `id` from conventional commit messages
+func (c *Client) AutoBump(ctx context.Context, id string, commits []string) (*api.Release, error) {
+	form := url.Values{"commits": commits}
+	res := new(api.Release)
+	return res, c.write(ctx, "POST", "/v1/"+url.PathEscape(id)+"/bump/auto", nil, form, res)
+}
+
+// History lists the versions of project `id`
+func (c *Client) History(ctx context.Context, id string) (*api.Archive, error) {
+	res := new(api.Archive)
+	return res, c.read(ctx, "/v1/"+url.PathEscape(id)+"/history", nil, res)
+}
+
+// Increment increases counter `name` of project `id` and returns the new
+// value, reset {never, major, minor} changes the reset policy when not empty
+func (c *Client) Increment(ctx context.Context, id, name, reset string) (*api.Counter, error) {
+	form := url.Values{}
+	if reset != "" {
+		form.Set("reset", reset)
+	}
+	res := new(api.Counter)
+	return res, c.write(ctx, "POST", "/v1/"+url.PathEscape(id)+"/counters/"+url.PathEscape(name), nil, form, res)
+}
+
+// Scheme returns the versioning scheme of project `id`
+func (c *Client) Scheme(ctx context.Context, id string) (*api.Scheme, error) {
+	res := new(api.Scheme)
+	return res, c.read(ctx, "/v1/"+url.PathEscape(id)+"/scheme", nil, res)
+}
+
+// Resolve returns the highest version of project `id` matching a range, e.g.
+// ">=1.2.0 <2.0.0", yanked versions are never returned
+func (c *Client) Resolve(ctx context.Context, id, rng string, prerelease bool) (*api.Versioning, error) {
+	q := url.Values{"range": {rng}}
+	if prerelease {
+		q.Set("prerelease", "true")
+	}
+	res := new(api.Versioning)
+	return res, c.read(ctx, "/v1/"+url.PathEscape(id)+"/resolve", q, res)
+}
+
+// Delete moves project `id` to the trash, it can be restored until the
+// retention period ends
+func (c *Client) Delete(ctx context.Context, id string) error {
+	return c.write(ctx, "DELETE", "/v1/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// Purge deletes project `id` permanently, it requires the administrator token
+func (c *Client) Purge(ctx context.Context, id string) error {
+	q := url.Values{"purge": {"true"}}
+	return c.write(ctx, "DELETE", "/v1/"+url.PathEscape(id), q, nil, nil)
+}
+
+// Restore restores soft deleted project `id`
+func (c *Client) Restore(ctx context.Context, id string) error {
+	return c.write(ctx, "POST", "/v1/"+url.PathEscape(id)+"/restore", nil, nil, nil)
+}
+
+// read sends an idempotent GET request, it is retried with exponential
+// backoff on network errors and 5xx responses
+func (c *Client) read(ctx context.Context, path string, q url.Values, res interface{}) error {
+	return c.do(ctx, "GET", path, q, nil, true, res)
+}
+
+// write sends a request changing the project, it is only retried when it
+// could not be sent so a bump is never applied twice
+func (c *Client) write(ctx context.Context, method, path string, q, form url.Values, res interface{}) error {
+	return c.do(ctx, method, path, q, form, false, res)
+}
+
+// do sends a request and decodes the json response into res
+func (c *Client) do(ctx context.Context, method, path string, q, form url.Values, idempotent bool, res interface{}) error {
+	if q == nil {
+		q = url.Values{}
+	}
+	q.Set("output", "json")
+	u := c.BaseURL + path + "?" + q.Encode()
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, method, u, form, res)
+		if !retryable(err, idempotent) || attempt >= c.Retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// send performs a single request
+func (c *Client) send(ctx context.Context, method, u string, form url.Values, res interface{}) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &netError{err}
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &netError{err}
+	}
+	if resp.StatusCode != http.StatusOK {
+		e := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(b))}
+		var w api.Warning
+		if json.Unmarshal(b, &w) == nil && w.Error != "" {
+			e.Message, e.RequestID = w.Error, w.RequestID
+		}
+		if e.Message == "" {
+			e.Message = http.StatusText(resp.StatusCode)
+		}
+		e.Err = api.Known(e.Message)
+		return e
+	}
+	if res == nil {
+		return nil
+	}
+	if err := json.Unmarshal(b, res); err != nil {
+		return ErrUnexpectedResponse
+	}
+	return nil
+}
+
+// netError marks transport errors
+type netError struct {
+	err error
+}
+
+func (e *netError) Error() string { return e.err.Error() }
+func (e *netError) Unwrap() error { return e.err }
+
+// unsent reports whether the request failed before it was sent, e.g. the
+// host name could not be resolved or the connection was refused
+func (e *netError) unsent() bool {
+	var op *net.OpError
+	if errors.As(e.err, &op) && op.Op == "dial" {
+		return true
+	}
+	var dns *net.DNSError
+	return errors.As(e.err, &dns)
+}
+
+// retryable checks if a request should be sent again, requests that are not
+// idempotent may have been applied unless they were never sent
+func retryable(err error, idempotent bool) bool {
+	switch e := err.(type) {
+	case *netError:
+		return idempotent || e.unsent()
+	case *Error:
+		if !idempotent {
+			return false
+		}
+		switch e.StatusCode {
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
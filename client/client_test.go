@@ -0,0 +1,243 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samuelngs/semver/backend"
+	"github.com/samuelngs/semver/handler/v1"
+	"github.com/samuelngs/semver/pkg/api"
+)
+
+const adminToken = "test-admin-token"
+
+// server starts the v1 router on a temporary bolt file and returns a client
+// of it with the administrator token
+func server(t *testing.T) (*Client, func()) {
+	dir, err := ioutil.TempDir("", "semver-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("SEMVER_BACKEND_ADDR", filepath.Join(dir, "semver.db"))
+	os.Setenv("SEMVER_ADMIN_TOKEN", adminToken)
+	m := backend.New()
+	if err := m.Use(new(backend.Bolt)); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	r := v1.New(m, engine)
+	srv := httptest.NewServer(engine)
+	c := New(srv.URL, adminToken)
+	c.Retries = 0
+	return c, func() {
+		srv.Close()
+		r.Close()
+		m.Close()
+		os.Unsetenv("SEMVER_BACKEND_ADDR")
+		os.Unsetenv("SEMVER_ADMIN_TOKEN")
+		os.RemoveAll(dir)
+	}
+}
+
+func TestClient(t *testing.T) {
+	c, done := server(t)
+	defer done()
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Project
+	if created.Version != "1.2.3" || id == "" {
+		t.Fatalf("Create = %+v", created)
+	}
+	if got, err := c.Get(ctx, id); err != nil || got.Version != "1.2.3" {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	if got, err := c.Set(ctx, id, "1.3.0"); err != nil || got.Version != "1.3.0" {
+		t.Fatalf("Set = %+v, %v", got, err)
+	}
+	for _, tt := range []struct {
+		typ  string
+		want string
+	}{
+		{Major, "2.0.0"},
+		{Minor, "2.1.0"},
+		{Patch, "2.1.1"},
+	} {
+		if got, err := c.Bump(ctx, id, tt.typ); err != nil || got.Version != tt.want {
+			t.Fatalf("Bump(%s) = %+v, %v, want %s", tt.typ, got, err, tt.want)
+		}
+	}
+	arch, err := c.History(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vers := []string{}
+	for _, v := range arch.Versions {
+		vers = append(vers, v.Version)
+	}
+	for _, want := range []string{"1.2.3", "1.3.0", "2.0.0", "2.1.0", "2.1.1"} {
+		if !contains(vers, want) {
+			t.Errorf("History = %v, missing %s", vers, want)
+		}
+	}
+	if got, err := c.Resolve(ctx, id, "<2.1.0", false); err != nil || got.Version != "2.0.0" {
+		t.Errorf("Resolve = %+v, %v", got, err)
+	}
+	if sc, err := c.Scheme(ctx, id); err != nil || sc.Name != api.SchemeSemver {
+		t.Errorf("Scheme = %+v, %v", sc, err)
+	}
+
+	if err := c.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, id); !IsNotFound(err) {
+		t.Fatalf("Get after Delete = %v, want not found", err)
+	}
+	if err := c.Restore(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.Get(ctx, id); err != nil || got.Version != "2.1.1" {
+		t.Fatalf("Get after Restore = %+v, %v", got, err)
+	}
+
+	anon := New(c.BaseURL, "wrong")
+	anon.Retries = 0
+	if err := anon.Purge(ctx, id); !errors.Is(err, api.ErrForbidden) {
+		t.Fatalf("Purge without token = %v, want %v", err, api.ErrForbidden)
+	}
+	if err := c.Purge(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Restore(ctx, id); !errors.Is(err, api.ErrProjectNotFound) {
+		t.Fatalf("Restore after Purge = %v, want %v", err, api.ErrProjectNotFound)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c, done := server(t)
+	defer done()
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"unknown project", func() error {
+			_, err := c.Get(ctx, "00000000-0000-0000-0000-000000000000")
+			return err
+		}, api.ErrProjectNotFound},
+		{"invalid uuid", func() error {
+			_, err := c.Get(ctx, "nope")
+			return err
+		}, api.ErrInvalidUUID},
+		{"invalid version", func() error {
+			_, err := c.Set(ctx, created.Project, "one")
+			return err
+		}, api.ErrInvalidVersioningFormat},
+		{"invalid counter reset", func() error {
+			_, err := c.Increment(ctx, created.Project, "build", "sometimes")
+			return err
+		}, api.ErrInvalidCounterReset},
+		{"invalid range", func() error {
+			_, err := c.Resolve(ctx, created.Project, "~>", false)
+			return err
+		}, api.ErrInvalidRange},
+	}
+	for _, tt := range tests {
+		err := tt.call()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+			continue
+		}
+		var e *Error
+		if !errors.As(err, &e) || e.StatusCode != http.StatusForbidden || e.Message != tt.want.Error() {
+			t.Errorf("%s: error = %#v, want status 403", tt.name, err)
+		}
+	}
+}
+
+// roundTripper counts requests and fails them with err, or responds with
+// status when err is nil
+type roundTripper struct {
+	n      int32
+	err    error
+	status int
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&rt.n, 1)
+	if rt.err != nil {
+		return nil, rt.err
+	}
+	rec := httptest.NewRecorder()
+	http.Error(rec, http.StatusText(rt.status), rt.status)
+	return rec.Result(), nil
+}
+
+func TestRetries(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		write  bool
+		want   int32
+	}{
+		{"read on dial error", refused, 0, false, 3},
+		{"write on dial error", refused, 0, true, 3},
+		{"read on reset", reset, 0, false, 3},
+		{"write on reset", reset, 0, true, 1},
+		{"read on 503", nil, http.StatusServiceUnavailable, false, 3},
+		{"write on 503", nil, http.StatusServiceUnavailable, true, 1},
+		{"read on 403", nil, http.StatusForbidden, false, 1},
+	}
+	for _, tt := range tests {
+		rt := &roundTripper{err: tt.err, status: tt.status}
+		c := New("http://semver.test", "")
+		c.Retries = 2
+		c.Backoff = time.Millisecond
+		c.HTTPClient = &http.Client{Transport: rt}
+		var err error
+		if tt.write {
+			_, err = c.Bump(context.Background(), "id", Patch)
+		} else {
+			_, err = c.Get(context.Background(), "id")
+		}
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+		if rt.n != tt.want {
+			t.Errorf("%s: %d requests, want %d", tt.name, rt.n, tt.want)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/samuelngs/semver/pkg/api"
+)
+
+// List of error messages, server errors are matched to the errors of pkg/api
+var (
+	ErrUnexpectedResponse = errors.New("unexpected response from server")
+)
+
+// Error represents an error response of the server
+type Error struct {
+	StatusCode int
+	Message    string
+	RequestID  string
+
+	// Err is the matching pkg/api error, nil when the message is unknown
+	Err error
+}
+
+// Error returns the error message
+func (e *Error) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("semver: %s (status %d, request %s)", e.Message, e.StatusCode, e.RequestID)
+	}
+	return fmt.Sprintf("semver: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// Unwrap returns the matching pkg/api error so errors.Is works with them
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsNotFound reports whether err means the project, component, channel, tag, counter,
+// reservation or version does not exist
+func IsNotFound(err error) bool {
+	return errors.Is(err, api.ErrProjectNotFound) ||
+		errors.Is(err, api.ErrChannelNotFound) ||
+		errors.Is(err, api.ErrTagNotFound) ||
+		errors.Is(err, api.ErrComponentNotFound) ||
+		errors.Is(err, api.ErrCounterNotFound) ||
+		errors.Is(err, api.ErrReservationNotFound) ||
+		errors.Is(err, api.ErrVersionNotFound)
+}
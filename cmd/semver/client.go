@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/samuelngs/semver/client"
+	"github.com/samuelngs/semver/pkg/api"
+	"gopkg.in/yaml.v2"
+)
+
+// exit codes of the client commands
+const (
+	exitError       = 1
+	exitUsage       = 2
+	exitNotFound    = 3
+	exitUnavailable = 4
+	exitOutOfSync   = 5 // write --check
+)
+
+const defaultServer = "http://localhost:4000"
+
+// remote holds the common flags of the client commands
+type remote struct {
+	fs     *flag.FlagSet
+	server string
+	token  string
+	config string
+	local  string
+	json   bool
+
+	// manifest files of the configuration file
+	files []string
+
+	// closers release local clients before exiting
+	closers []func()
+}
+
+// newRemote creates the flag set of a client command
+func newRemote(name, usage string) *remote {
+	r := &remote{fs: flag.NewFlagSet(name, flag.ContinueOnError)}
+	r.fs.StringVar(&r.server, "server", "", "semver server url (SEMVER_SERVER, default "+defaultServer+")")
+	r.fs.StringVar(&r.token, "token", "", "api token (SEMVER_TOKEN)")
+	r.fs.StringVar(&r.config, "config", "", "client configuration file (SEMVER_CLIENT_CONFIG, default ~/.semver.yaml)")
+	r.fs.StringVar(&r.local, "local", "", "work offline on a local bolt file, e.g. .semver (SEMVER_LOCAL)")
+	r.fs.BoolVar(&r.json, "json", false, "print json output")
+	r.fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: semver %s\n", usage)
+		r.fs.PrintDefaults()
+	}
+	return r
+}
+
+// parse parses flags placed before, between or after the positional
+// arguments and checks their count
+func (r *remote) parse(args []string, n int) []string {
+	var pos []string
+	for {
+		if err := r.fs.Parse(args); err == flag.ErrHelp {
+			os.Exit(0)
+		} else if err != nil {
+			os.Exit(exitUsage)
+		}
+		if r.fs.NArg() == 0 {
+			break
+		}
+		pos = append(pos, r.fs.Arg(0))
+		args = r.fs.Args()[1:]
+	}
+	if len(pos) != n {
+		r.fs.Usage()
+		os.Exit(exitUsage)
+	}
+	return pos
+}
+
+// settings reads the configuration file, flags take precedence over the
+// environment and the environment over the configuration file
+func (r *remote) settings() {
+	var file struct {
+		Server string   `yaml:"server"`
+		Token  string   `yaml:"token"`
+		Local  string   `yaml:"local"`
+		Files  []string `yaml:"files"`
+	}
+	path := first(r.config, os.Getenv("SEMVER_CLIENT_CONFIG"))
+	if path == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, ".semver.yaml")
+		}
+	}
+	if b, err := ioutil.ReadFile(path); err == nil {
+		if err := yaml.Unmarshal(b, &file); err != nil {
+			r.fail(fmt.Errorf("%s: %v", path, err))
+		}
+	} else if r.config != "" {
+		r.fail(err)
+	}
+	r.server = first(r.server, os.Getenv("SEMVER_SERVER"), file.Server, defaultServer)
+	r.token = first(r.token, os.Getenv("SEMVER_TOKEN"), file.Token)
+	r.local = first(r.local, os.Getenv("SEMVER_LOCAL"), file.Local)
+	r.files = file.Files
+}
+
+// client creates the api client of the server, or of the local file in
+// offline mode, the returned func releases the client
+func (r *remote) client() (*client.Client, func()) {
+	r.settings()
+	if r.local != "" {
+		return r.localClient(r.local)
+	}
+	return client.New(r.server, r.token), func() {}
+}
+
+// print writes the response as json or in its text format
+func (r *remote) print(v interface{}) {
+	if r.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(v)
+		return
+	}
+	fmt.Println(v)
+}
+
+// fail prints the error and exits with the matching exit code
+func (r *remote) fail(err error) {
+	code := exitError
+	var e *client.Error
+	var u *url.Error
+	isServer := errors.As(err, &e)
+	switch {
+	case client.IsNotFound(err):
+		code = exitNotFound
+	case isServer && e.StatusCode >= 500, errors.As(err, &u), err == context.DeadlineExceeded:
+		code = exitUnavailable
+	}
+	msg := err.Error()
+	if isServer {
+		msg = e.Message
+	}
+	if r.json {
+		res := map[string]string{"error": msg}
+		if isServer && e.RequestID != "" {
+			res["request_id"] = e.RequestID
+		}
+		b, _ := json.Marshal(res)
+		fmt.Fprintln(os.Stderr, string(b))
+	} else {
+		fmt.Fprintf(os.Stderr, "semver: %s\n", msg)
+	}
+	r.exit(code)
+}
+
+// exit releases the local clients and exits with code
+func (r *remote) exit(code int) {
+	for _, done := range r.closers {
+		done()
+	}
+	os.Exit(code)
+}
+
+// first returns the first non empty string
+func first(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// create creates a project and prints its id
+func create(args []string) {
+	r := newRemote("new", "new [--version 1.0.0] [--scheme semver|calver|build] [--format YYYY.MM.MICRO] [flags]")
+	version := r.fs.String("version", "", "initial version, 0.0.1 when empty")
+	scheme := r.fs.String("scheme", "", "versioning scheme, semver, calver or build")
+	format := r.fs.String("format", "", "calver format, YYYY.MM.MICRO when empty")
+	r.parse(args, 0)
+	c, done := r.client()
+	defer done()
+	res, err := c.CreateScheme(context.Background(), *version, *scheme, *format)
+	if err != nil {
+		r.fail(err)
+	}
+	r.print(res)
+}
+
+// get prints the current version of a project
+func get(args []string) {
+	r := newRemote("get", "get <id> [flags]")
+	pos := r.parse(args, 1)
+	c, done := r.client()
+	defer done()
+	res, err := c.Get(context.Background(), pos[0])
+	if err != nil {
+		r.fail(err)
+	}
+	r.print(res)
+}
+
+// bump increases the version of a project, with --git-tag the new version
+// is tagged in the local repository, with --type auto the bump type is
+// derived from the commits since the latest version tag
+func bump(args []string) {
+	r := newRemote("bump", "bump <id> [--type major|minor|patch|auto] [--git-tag] [flags]")
+	typ := r.fs.String("type", client.Patch, "bump type {major, minor, patch, auto}")
+	tag := r.fs.Bool("git-tag", false, "create an annotated tag of the new version in the repository")
+	repo := r.fs.String("repo", ".", "git repository")
+	prefix := r.fs.String("tag-prefix", "v", "git tag prefix")
+	message := r.fs.String("tag-message", defaultTagMessage, "tag message template, fields: .Version .Tag .Previous .Type .Changelog .Commits")
+	pos := r.parse(args, 1)
+	switch *typ {
+	case client.Major, client.Minor, client.Patch, "auto":
+	default:
+		r.fs.Usage()
+		os.Exit(exitUsage)
+	}
+	tmpl, err := template.New("tag").Parse(*message)
+	if err != nil {
+		r.fail(err)
+	}
+	// read the repository before bumping so a bad repository does not leave
+	// a bumped version without a tag
+	data := &tagData{Type: *typ}
+	if *tag || *typ == "auto" {
+		if data.Previous, data.Commits, err = gitCommits(*repo, *prefix); err != nil {
+			r.fail(err)
+		}
+	}
+	c, done := r.client()
+	defer done()
+	var res interface{}
+	var ver *api.Versioning
+	if *typ == "auto" {
+		rel, err := c.AutoBump(context.Background(), pos[0], data.Commits)
+		if err != nil {
+			r.fail(err)
+		}
+		res, ver, data.Type = rel, rel.Version, rel.Type
+		data.Changelog = changelog(rel.Changelog, data.Commits)
+	} else {
+		if ver, err = c.Bump(context.Background(), pos[0], *typ); err != nil {
+			r.fail(err)
+		}
+		res = ver
+		data.Changelog = changelog(nil, data.Commits)
+	}
+	if *tag {
+		data.Version, data.Tag = ver.Version, *prefix+ver.Version
+		if err := gitTag(*repo, tmpl, data); err != nil {
+			r.fail(err)
+		}
+		fmt.Fprintf(os.Stderr, "tagged %s\n", data.Tag)
+	}
+	r.print(res)
+}
+
+// importGit seeds the history of a project from the semver tags of a
+// repository, `new` creates the project from the lowest tagged version
+func importGit(args []string) {
+	r := newRemote("import-git", "import-git <id|new> [--repo .] [--tag-prefix v] [flags]")
+	repo := r.fs.String("repo", ".", "git repository")
+	prefix := r.fs.String("tag-prefix", "v", "git tag prefix")
+	pos := r.parse(args, 1)
+	vers, err := gitVersions(*repo, *prefix, false)
+	if err != nil {
+		r.fail(err)
+	}
+	if len(vers) == 0 {
+		r.fail(fmt.Errorf("%s: no tags matching %s<version>", *repo, *prefix))
+	}
+	c, done := r.client()
+	defer done()
+	// versions are set in ascending order so the highest becomes current
+	strs := make([]string, len(vers))
+	for i, ver := range vers {
+		strs[i] = ver.String()
+	}
+	id, err := replay(c, pos[0], &api.Scheme{Name: api.SchemeSemver}, strs)
+	if err != nil {
+		r.fail(err)
+	}
+	res, err := c.History(context.Background(), id)
+	if err != nil {
+		r.fail(err)
+	}
+	if r.json {
+		r.print(map[string]interface{}{"project": id, "versions": res.Versions})
+		return
+	}
+	fmt.Println(id)
+	fmt.Fprintf(os.Stderr, "imported %d versions\n", len(res.Versions))
+}
+
+// set sets the version of a project
+func set(args []string) {
+	r := newRemote("set", "set <id> <version> [flags]")
+	pos := r.parse(args, 2)
+	c, done := r.client()
+	defer done()
+	res, err := c.Set(context.Background(), pos[0], pos[1])
+	if err != nil {
+		r.fail(err)
+	}
+	r.print(res)
+}
+
+// history lists the versions of a project
+func history(args []string) {
+	r := newRemote("history", "history <id> [flags]")
+	pos := r.parse(args, 1)
+	c, done := r.client()
+	defer done()
+	res, err := c.History(context.Background(), pos[0])
+	if err != nil {
+		r.fail(err)
+	}
+	if r.json {
+		r.print(res)
+		return
+	}
+	fmt.Print(res)
+}
+
+// counter increases a build number counter of a project and prints the new value
+func counter(args []string) {
+	r := newRemote("counter", "counter <id> <name> [--reset never|major|minor] [flags]")
+	reset := r.fs.String("reset", "", "start again from 1 on a new major or minor version")
+	pos := r.parse(args, 2)
+	c, done := r.client()
+	defer done()
+	res, err := c.Increment(context.Background(), pos[0], pos[1], *reset)
+	if err != nil {
+		r.fail(err)
+	}
+	r.print(res)
+}
+
+// remove deletes a project
+func remove(args []string) {
+	r := newRemote("delete", "delete <id> [--purge] [flags]")
+	purge := r.fs.Bool("purge", false, "delete permanently, requires an administrator token")
+	pos := r.parse(args, 1)
+	c, done := r.client()
+	defer done()
+	var err error
+	if *purge {
+		err = c.Purge(context.Background(), pos[0])
+	} else {
+		err = c.Delete(context.Background(), pos[0])
+	}
+	if err != nil {
+		r.fail(err)
+	}
+	if r.json {
+		r.print(map[string]string{"project": pos[0], "status": "deleted"})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/blang/semver"
+	"github.com/samuelngs/semver/pkg/api"
+)
+
+const defaultTagMessage = "Release {{.Version}}\n\n{{.Changelog}}"
+
+// tagData is passed to the tag message template
+type tagData struct {
+	Version   string
+	Tag       string
+	Previous  string
+	Type      string
+	Changelog string
+	Commits   []string
+}
+
+// git runs a git command in the repository and returns its trimmed output
+func git(repo string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// gitVersions returns the semver tags with the prefix sorted by version,
+// only tags reachable from HEAD are returned when merged is set
+func gitVersions(repo, prefix string, merged bool) ([]semver.Version, error) {
+	args := []string{"tag", "--list", prefix + "*"}
+	if merged {
+		args = append(args, "--merged", "HEAD")
+	}
+	out, err := git(repo, args...)
+	if err != nil {
+		return nil, err
+	}
+	var vers []semver.Version
+	for _, tag := range strings.Fields(out) {
+		if ver, err := semver.Parse(strings.TrimPrefix(tag, prefix)); err == nil {
+			vers = append(vers, ver)
+		}
+	}
+	sort.Sort(semver.Versions(vers))
+	return vers, nil
+}
+
+// gitCommits returns the commit messages after the latest semver tag
+// reachable from HEAD, or all commit messages when there is no such tag
+func gitCommits(repo, prefix string) (string, []string, error) {
+	vers, err := gitVersions(repo, prefix, true)
+	if err != nil {
+		return "", nil, err
+	}
+	var previous string
+	args := []string{"log", "--format=%B%x00"}
+	if len(vers) > 0 {
+		previous = prefix + vers[len(vers)-1].String()
+		args = append(args, previous+"..HEAD")
+	}
+	out, err := git(repo, args...)
+	if err != nil {
+		return "", nil, err
+	}
+	var msgs []string
+	for _, msg := range strings.Split(out, "\x00") {
+		if msg = strings.TrimSpace(msg); msg != "" {
+			msgs = append(msgs, msg)
+		}
+	}
+	return previous, msgs, nil
+}
+
+// changelog renders the release notes of a bump, the sections of a
+// conventional commit release or the commit subjects otherwise
+func changelog(sections []*api.ChangelogSection, commits []string) string {
+	var out string
+	if len(sections) > 0 {
+		for _, s := range sections {
+			out += fmt.Sprintf("### %s\n\n", s.Title)
+			for _, n := range s.Notes {
+				out += fmt.Sprintf("- %s\n", n)
+			}
+			out += "\n"
+		}
+		return strings.TrimSpace(out)
+	}
+	for _, c := range commits {
+		out += fmt.Sprintf("- %s\n", strings.SplitN(c, "\n", 2)[0])
+	}
+	return strings.TrimSpace(out)
+}
+
+// gitTag creates an annotated tag at HEAD
+func gitTag(repo string, tmpl *template.Template, data *tagData) error {
+	var msg bytes.Buffer
+	if err := tmpl.Execute(&msg, data); err != nil {
+		return err
+	}
+	_, err := git(repo, "tag", "--annotate", "--cleanup=whitespace", data.Tag, "--message", strings.TrimSpace(msg.String()))
+	return err
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"reflect"
+	"testing"
+	"text/template"
+
+	"github.com/samuelngs/semver/pkg/api"
+)
+
+func TestChangelog(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []*api.ChangelogSection
+		commits  []string
+		want     string
+	}{
+		{"empty", nil, nil, ""},
+		{
+			"conventional",
+			[]*api.ChangelogSection{
+				{Type: "breaking", Title: "Breaking Changes", Notes: []string{"drop v0"}},
+				{Type: "feat", Title: "Features", Notes: []string{"**api:** add search", "add export"}},
+			},
+			[]string{"ignored"},
+			"### Breaking Changes\n\n- drop v0\n\n### Features\n\n- **api:** add search\n- add export",
+		},
+		{
+			"commit subjects",
+			nil,
+			[]string{"update readme\n\nlong body", "fix typo"},
+			"- update readme\n- fix typo",
+		},
+	}
+	for _, tt := range tests {
+		if got := changelog(tt.sections, tt.commits); got != tt.want {
+			t.Errorf("%s: changelog = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo, err := ioutil.TempDir("", "semver-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repo)
+	run := func(args ...string) string {
+		out, err := git(repo, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	run("init", "--quiet")
+	run("config", "user.name", "semver")
+	run("config", "user.email", "semver@example.com")
+	run("commit", "--quiet", "--allow-empty", "--message", "chore: init")
+	run("tag", "v1.0.0")
+	run("tag", "latest")
+	run("commit", "--quiet", "--allow-empty", "--message", "feat: add search\n\nBREAKING CHANGE: new index")
+	run("tag", "v1.10.0")
+	run("tag", "v1.2.0")
+	run("checkout", "--quiet", "-b", "topic")
+	run("commit", "--quiet", "--allow-empty", "--message", "fix: off by one")
+	run("tag", "v2.0.0")
+	run("checkout", "--quiet", "-")
+	run("commit", "--quiet", "--allow-empty", "--message", "fix: nil body")
+
+	vers, err := gitVersions(repo, "v", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, v := range vers {
+		got = append(got, v.String())
+	}
+	if want := []string{"1.0.0", "1.2.0", "1.10.0", "2.0.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("gitVersions = %v, want %v", got, want)
+	}
+	if vers, err = gitVersions(repo, "v", true); err != nil || len(vers) != 3 {
+		t.Errorf("gitVersions merged = %v, %v, want 3 versions", vers, err)
+	}
+
+	previous, msgs, err := gitCommits(repo, "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != "v1.10.0" || !reflect.DeepEqual(msgs, []string{"fix: nil body"}) {
+		t.Errorf("gitCommits = %q, %q", previous, msgs)
+	}
+
+	tmpl := template.Must(template.New("tag").Parse(defaultTagMessage))
+	data := &tagData{Version: "1.10.1", Tag: "v1.10.1", Changelog: changelog(nil, msgs)}
+	if err := gitTag(repo, tmpl, data); err != nil {
+		t.Fatal(err)
+	}
+	if msg := run("tag", "--list", "--format=%(contents)", "v1.10.1"); msg != "Release 1.10.1\n\n- fix: nil body" {
+		t.Errorf("tag message = %q", msg)
+	}
+	if err := gitTag(repo, tmpl, data); err == nil {
+		t.Error("gitTag of an existing tag expected an error")
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samuelngs/semver/backend"
+	"github.com/samuelngs/semver/client"
+	"github.com/samuelngs/semver/handler/v1"
+	"github.com/samuelngs/semver/pkg/admin"
+	"github.com/samuelngs/semver/pkg/api"
+)
+
+// localTransport serves client requests with an in-process router, the
+// requests are sent by the administrator
+type localTransport struct {
+	h http.Handler
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *localTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.h.ServeHTTP(rec, admin.Grant(req))
+	return rec.Result(), nil
+}
+
+// localClient creates a client of the v1 router running on a local bolt
+// file, the local user is the administrator of the file. The returned func
+// stops the router and closes the file, it is also called on exit.
+func (r *remote) localClient(path string) (*client.Client, func()) {
+	m := backend.New()
+	if err := m.Use(&backend.Bolt{File: path}); err != nil {
+		r.fail(fmt.Errorf("%s: %v", path, err))
+	}
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	router := v1.New(m, engine)
+	var once sync.Once
+	done := func() {
+		once.Do(func() {
+			router.Close()
+			m.Close()
+		})
+	}
+	r.closers = append(r.closers, done)
+	c := client.New("http://local", "")
+	c.Retries = 0
+	c.HTTPClient = &http.Client{Transport: &localTransport{engine}}
+	return c, done
+}
+
+// push copies the version history of a local project to the server, `--to
+// new` creates a project on the server. Tags, channels, components, flags,
+// counters and events are not copied, a warning lists those of the project.
+func push(args []string) {
+	r := newRemote("push", "push <local id> [--to <id|new>] [--local .semver] [flags]")
+	to := r.fs.String("to", "new", "server project id, new creates a project")
+	pos := r.parse(args, 1)
+	r.settings()
+	src, done := r.localClient(first(r.local, ".semver"))
+	defer done()
+	dst := client.New(r.server, r.token)
+	ctx := context.Background()
+	cur, err := src.Get(ctx, pos[0])
+	if err != nil {
+		r.fail(err)
+	}
+	arch, err := src.History(ctx, pos[0])
+	if err != nil {
+		r.fail(err)
+	}
+	sc, err := src.Scheme(ctx, pos[0])
+	if err != nil {
+		r.fail(err)
+	}
+	// the history is sorted by the server, the current version is set again
+	// last when it is not the highest
+	vers := make([]string, 0, len(arch.Versions)+1)
+	for _, v := range arch.Versions {
+		vers = append(vers, v.Version)
+	}
+	if len(vers) == 0 || vers[len(vers)-1] != cur.Version {
+		vers = append(vers, cur.Version)
+	}
+	id, err := replay(dst, *to, sc, vers)
+	if err != nil {
+		r.fail(err)
+	}
+	if skipped := uncopied(arch); len(skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: not copied: %s\n", strings.Join(skipped, ", "))
+	}
+	fmt.Fprintln(os.Stderr, "warning: tags, channels and components are not copied")
+	if r.json {
+		r.print(map[string]interface{}{"project": id, "version": cur.Version, "versions": len(arch.Versions)})
+		return
+	}
+	fmt.Println(id)
+	fmt.Fprintf(os.Stderr, "pushed %d versions\n", len(arch.Versions))
+}
+
+// uncopied describes the data of a local history that push does not copy
+func uncopied(arch *api.Archive) []string {
+	var flagged, counted int
+	for _, v := range arch.Versions {
+		if v.Yanked || v.Deprecated {
+			flagged++
+		}
+		if len(v.Counters) > 0 {
+			counted++
+		}
+	}
+	var res []string
+	if flagged > 0 {
+		res = append(res, fmt.Sprintf("yank or deprecation flags of %d versions", flagged))
+	}
+	if counted > 0 {
+		res = append(res, fmt.Sprintf("counters of %d versions", counted))
+	}
+	if len(arch.Events) > 0 {
+		res = append(res, fmt.Sprintf("%d events", len(arch.Events)))
+	}
+	return res
+}
+
+// replay sets versions in order on project `id`, `new` creates the project
+// with scheme sc from the first version, an existing project must use sc
+func replay(c *client.Client, id string, sc *api.Scheme, vers []string) (string, error) {
+	ctx := context.Background()
+	if id == "new" {
+		res, err := c.CreateScheme(ctx, vers[0], sc.Name, sc.Format)
+		if err != nil {
+			return "", err
+		}
+		id, vers = res.Project, vers[1:]
+	} else {
+		cur, err := c.Scheme(ctx, id)
+		if err != nil {
+			return id, err
+		}
+		if cur.Name != sc.Name || cur.Format != sc.Format {
+			return id, fmt.Errorf("project %s uses the %s scheme, not %s", id, cur, sc)
+		}
+	}
+	for _, ver := range vers {
+		if _, err := c.Set(ctx, id, ver); err != nil {
+			return id, err
+		}
+	}
+	return id, nil
+}
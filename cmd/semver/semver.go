@@ -1,20 +1,216 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
 	"github.com/samuelngs/semver/backend"
+	"github.com/samuelngs/semver/pkg/config"
 	"github.com/samuelngs/semver/pkg/env"
+	"github.com/samuelngs/semver/pkg/logger"
 	"github.com/samuelngs/semver/server"
 )
 
-var defaultAddr = ":4000"
-
 func main() {
+	args := os.Args[1:]
+	cmd := "serve"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "serve":
+		serve(args)
+	case "config":
+		if len(args) == 0 || args[0] != "print" {
+			fatal(fmt.Errorf("usage: semver config print [flags]"))
+		}
+		printConfig(args[1:])
+	case "backup":
+		backup(args)
+	case "compact":
+		compact(args)
+	case "new":
+		create(args)
+	case "get":
+		get(args)
+	case "bump":
+		bump(args)
+	case "set":
+		set(args)
+	case "history":
+		history(args)
+	case "counter":
+		counter(args)
+	case "delete":
+		remove(args)
+	case "import-git":
+		importGit(args)
+	case "write":
+		write(args)
+	case "push":
+		push(args)
+	default:
+		fatal(fmt.Errorf("unknown command %q", cmd))
+	}
+}
+
+// serve starts the api server and shuts it down gracefully on SIGINT or SIGTERM
+func serve(args []string) {
+	conf := load(args)
 
 	// create api server
 	api := server.New(storage())
 
+	srv := &http.Server{
+		Addr:    conf.Get("SEMVER_LISTEN"),
+		Handler: api,
+	}
+	if conf.Get("SEMVER_TLS_CERT") != "" {
+		tlsConf, err := api.TLS()
+		if err != nil {
+			fatal(err)
+		}
+		srv.TLSConfig = tlsConf
+	}
+
 	// start server
-	api.Run(defaultAddr)
+	errs := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			errs <- srv.ListenAndServeTLS("", "")
+		} else {
+			errs <- srv.ListenAndServe()
+		}
+	}()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-errs:
+		api.Close()
+		fatal(err)
+	case s := <-sig:
+		logger.Info("shutting down", logger.Fields{"signal": s.String()})
+	}
+
+	// drain in-flight requests, then stop background workers and storage
+	timeout, _ := time.ParseDuration(conf.Get("SEMVER_SHUTDOWN_TIMEOUT"))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Error("shutdown timed out", logger.Fields{"error": err.Error()})
+	}
+	if err := api.Close(); err != nil {
+		fatal(err)
+	}
+}
+
+// printConfig prints the effective configuration with secrets redacted
+func printConfig(args []string) {
+	conf := load(args)
+	out, err := conf.Print()
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Print(out)
+}
+
+// backup downloads a hot backup of the bolt database from a running server
+func backup(args []string) {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	addr := fs.String("server", "http://localhost:4000", "semver server url")
+	token := fs.String("token", os.Getenv("SEMVER_ADMIN_TOKEN"), "administrator token")
+	out := fs.String("o", "", "output file, standard output when empty")
+	fs.Parse(args)
+
+	req, err := http.NewRequest("GET", strings.TrimRight(*addr, "/")+"/debug/backend/backup", nil)
+	if err != nil {
+		fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+*token)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(res.Body)
+		fatal(fmt.Errorf("backup: %s: %s", res.Status, strings.TrimSpace(string(msg))))
+	}
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600); err != nil {
+			fatal(err)
+		}
+	}
+	if _, err := io.Copy(w, res.Body); err != nil {
+		fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		fatal(err)
+	}
+}
+
+// compact rewrites a bolt database to reclaim the space of deleted projects,
+// the server using the file must be stopped
+func compact(args []string) {
+	fs := flag.NewFlagSet("compact", flag.ExitOnError)
+	out := fs.String("o", "", "output file, the database is replaced in place when empty")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: semver compact [-o output] <database>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	src, dst := fs.Arg(0), *out
+	if dst == "" {
+		dst = src + ".compact"
+	}
+	if err := backend.CompactBolt(src, dst); err != nil {
+		if err != os.ErrExist {
+			os.Remove(dst)
+		}
+		fatal(fmt.Errorf("compact: %v", err))
+	}
+	before, _ := os.Stat(src)
+	after, _ := os.Stat(dst)
+	if *out == "" {
+		if err := os.Rename(dst, src); err != nil {
+			fatal(err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "compacted %d -> %d bytes\n", before.Size(), after.Size())
+}
+
+// load reads and validates the configuration and exports it to the environment
+func load(args []string) *config.Config {
+	conf, err := config.Load(args)
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	} else if err != nil {
+		fatal(err)
+	}
+	if err := conf.Export(); err != nil {
+		fatal(err)
+	}
+	return conf
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "semver: %v\n", err)
+	os.Exit(1)
 }
 
 func storage() backend.Client {
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/samuelngs/semver/pkg/manifest"
+)
+
+// list is a repeatable string flag
+type list []string
+
+func (l *list) String() string     { return strings.Join(*l, ",") }
+func (l *list) Set(s string) error { *l = append(*l, s); return nil }
+
+// write updates the version of manifest files to the current version of a
+// project, --check only reports the files that are out of sync
+func write(args []string) {
+	r := newRemote("write", "write <id> [--file path[:format[:key]]]... [--check] [flags]")
+	var specs list
+	r.fs.Var(&specs, "file", "manifest file, repeatable, format {json, yaml, toml, xml, go, text} and key are optional")
+	check := r.fs.Bool("check", false, "fail when the files are out of sync with the server")
+	pos := r.parse(args, 1)
+	c, done := r.client()
+	defer done()
+	if len(specs) == 0 {
+		specs = r.files
+	}
+	if len(specs) == 0 {
+		for _, name := range manifest.Known {
+			if _, err := os.Stat(name); err == nil {
+				specs = append(specs, name)
+			}
+		}
+	}
+	if len(specs) == 0 {
+		r.fail(fmt.Errorf("no manifest files, use --file or files in the configuration file"))
+	}
+	files := make([]*manifest.File, len(specs))
+	for i, spec := range specs {
+		f, err := manifest.Parse(spec)
+		if err != nil {
+			r.fail(err)
+		}
+		files[i] = f
+	}
+	ver, err := c.Get(context.Background(), pos[0])
+	if err != nil {
+		r.fail(err)
+	}
+	type result struct {
+		Path    string `json:"path"`
+		Version string `json:"version,omitempty"`
+		Status  string `json:"status"`
+	}
+	results := make([]*result, len(files))
+	sync := true
+	for i, f := range files {
+		res := &result{Path: f.Path, Status: "ok"}
+		if *check {
+			cur, err := f.Read()
+			if err != nil {
+				r.fail(err)
+			}
+			res.Version = cur
+			if cur != ver.Version {
+				res.Status, sync = "out of sync", false
+			}
+		} else {
+			changed, err := f.Write(ver.Version)
+			if err != nil {
+				r.fail(err)
+			}
+			res.Version = ver.Version
+			if changed {
+				res.Status = "updated"
+			}
+		}
+		results[i] = res
+	}
+	if r.json {
+		r.print(map[string]interface{}{"version": ver.Version, "files": results})
+	} else {
+		for _, res := range results {
+			fmt.Printf("%s\t%s\t%s\n", res.Path, res.Version, res.Status)
+		}
+	}
+	if !sync {
+		r.exit(exitOutOfSync)
+	}
+}
@@ -0,0 +1,174 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/gin-gonic/gin"
+	"github.com/samuelngs/semver/backend"
+)
+
+// maxBatch is the maximum number of operations of a batch, datastore
+// transactions span at most 25 entity groups
+const maxBatch = 25
+
+// Operation is a bump or set of a batch
+type Operation struct {
+	Project   string `json:"project"`
+	Component string `json:"component"`
+	Channel   string `json:"channel"`
+	Op        string `json:"op"`
+	Type      string `json:"type"`
+	Version   string `json:"version"`
+	Expect    string `json:"expect"`
+}
+
+// operation validates an operation of the batch and returns the scope, the
+// current and the next version
+func (r *Router) operation(o *Operation, sc *Scheme) ([]string, semver.Version, semver.Version, error) {
+	var cur, ver semver.Version
+	comp, err := r.component(o.Component)
+	if err != nil {
+		return nil, cur, ver, err
+	}
+	ch, err := r.channel(o.Channel)
+	if err != nil {
+		return nil, cur, ver, err
+	}
+	scope := append(comp, ch...)
+	if cur, err = r.current(o.Project, scope); err != nil {
+		return nil, cur, ver, err
+	}
+	if o.Expect != "" {
+		expect, err := sc.parse(o.Expect)
+		if err != nil {
+			return nil, cur, ver, err
+		}
+		if !expect.Equals(cur) {
+			return nil, cur, ver, ErrVersionMismatch
+		}
+	}
+	switch o.Op {
+	case "bump":
+		if o.Type != "" && o.Type != "major" && o.Type != "minor" && o.Type != "patch" {
+			return nil, cur, ver, ErrInvalidBumpType
+		}
+		if ver, err = r.following(o.Project, sc, scope, cur, o.Type); err != nil {
+			return nil, cur, ver, err
+		}
+	case "set":
+		if ver, err = sc.parse(o.Version); err != nil {
+			return nil, cur, ver, err
+		}
+	default:
+		return nil, cur, ver, ErrInvalidOperation
+	}
+	return scope, cur, ver, nil
+}
+
+// event is an event of a batch operation, recorded once the batch is applied
+type event struct {
+	id    string
+	scope []string
+	ev    *Event
+}
+
+// batchScheme returns the versioning scheme of the project of an operation
+func (r *Router) batchScheme(id string) (*Scheme, error) {
+	if _, err := r.uuid(id); err != nil {
+		return nil, err
+	}
+	return r.scheme(id)
+}
+
+// Batch applies bump and set operations to several projects at once, the
+// operations are given as a json object {"operations": [{"project": "...",
+// "op": "bump", "type": "minor", "expect": "1.2.0"}]}. Nothing is written
+// unless every operation is valid and every expected version matches, the
+// writes are applied in one transaction and backends without transactions
+// reject batches.
+func (r *Router) Batch(c *gin.Context) {
+	defer r.release(c)
+	var body struct {
+		Operations []*Operation `json:"operations"`
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil || len(body.Operations) == 0 {
+		r.err(c, ErrInvalidBatch)
+		return
+	}
+	if len(body.Operations) > maxBatch {
+		r.err(c, ErrBatchTooLarge)
+		return
+	}
+	if r.authorize != nil {
+		for _, o := range body.Operations {
+			if !r.authorize(c, o.Project, "write") {
+				r.err(c, ErrAccessDenied)
+				return
+			}
+		}
+	}
+	res := &Batch{Results: make([]*BatchResult, 0, len(body.Operations))}
+	checks := make([]*backend.Check, 0, len(body.Operations))
+	ops := make([]*backend.Op, 0, len(body.Operations)*2)
+	evs := make([]*event, 0, len(body.Operations))
+	seen := make(map[string]bool)
+	failed := false
+	for _, o := range body.Operations {
+		item := &BatchResult{Project: o.Project, Component: o.Component, Channel: o.Channel}
+		res.Results = append(res.Results, item)
+		var scope []string
+		var cur, ver semver.Version
+		sc, err := r.batchScheme(o.Project)
+		if err == nil {
+			scope, cur, ver, err = r.operation(o, sc)
+		}
+		if err == nil {
+			if k := o.Project + ":" + strings.Join(scope, ":"); seen[k] {
+				err = ErrDuplicateOperation
+			} else {
+				seen[k] = true
+			}
+		}
+		if err != nil {
+			item.Error = err.Error()
+			failed = true
+			continue
+		}
+		item.Previous, item.Version = sc.format(cur), sc.format(ver)
+		evs = append(evs, &event{o.Project, scope, &Event{
+			Type:     "batch",
+			Version:  item.Version,
+			Previous: item.Previous,
+			Reason:   strings.TrimSpace(o.Op + " " + o.Type),
+		}})
+		checks = append(checks, &backend.Check{Key: r.key(o.Project, scope, "version"), Val: cur.String()})
+		ops = append(ops,
+			&backend.Op{Key: r.key(o.Project, scope, "version"), Val: ver.String()},
+			&backend.Op{Key: r.key(o.Project, scope, "archive", ver.String()), Val: ver.String()},
+		)
+	}
+	if failed {
+		res.Error = ErrBatchRejected.Error()
+		r.respond(c, http.StatusConflict, res)
+		return
+	}
+	if err := r.m.Apply(checks, ops); err == backend.ErrConflict {
+		res.Error = err.Error()
+		r.respond(c, http.StatusConflict, res)
+		return
+	} else if err != nil {
+		r.err(c, err)
+		return
+	}
+	res.Applied, res.Atomic = true, true
+	for _, e := range evs {
+		if err := r.record(e.id, e.scope, e.ev); err != nil {
+			r.err(c, err)
+			return
+		}
+	}
+	r.echo(c, res)
+}
@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// batch posts a batch and decodes the json output
+func batch(t *testing.T, s *server, body string) (int, *Batch) {
+	t.Helper()
+	code, out := s.send("POST", "/v1/batch?output=json", "application/json", body)
+	res := new(Batch)
+	if err := json.Unmarshal([]byte(out), res); err != nil {
+		t.Fatalf("batch %s: %v: %s", body, err, out)
+	}
+	return code, res
+}
+
+func TestBatch(t *testing.T) {
+	s, done := serve(t)
+	defer done()
+	a, b := s.create("version=1.1.0"), s.create("version=0.1.0")
+	s.must("POST", "/v1/"+b+"/components/api", url.Values{"version": {"1.4.2"}})
+
+	code, res := batch(t, s, fmt.Sprintf(`{"operations": [
+		{"project": %q, "op": "bump", "type": "minor", "expect": "1.1.0"},
+		{"project": %q, "component": "api", "op": "set", "version": "2.0.0"}
+	]}`, a, b))
+	if code != http.StatusOK || !res.Applied || !res.Atomic || res.Error != "" {
+		t.Fatalf("Batch = %d %+v", code, res)
+	}
+	want := []string{a + " 1.1.0 -> 1.2.0", b + "/api 1.4.2 -> 2.0.0"}
+	for i, item := range res.Results {
+		if got := item.String(); got != want[i] {
+			t.Errorf("result %d = %q, want %q", i, got, want[i])
+		}
+	}
+	if got := s.must("GET", "/v1/"+a, nil); got != "1.2.0" {
+		t.Errorf("version of a = %q, want 1.2.0", got)
+	}
+	if got := s.must("GET", "/v1/"+b+"/components/api", nil); got != "2.0.0" {
+		t.Errorf("version of b/api = %q, want 2.0.0", got)
+	}
+	var arch Archive
+	s.json("GET", "/v1/"+a+"/history", nil, &arch)
+	if n := len(arch.Events); n != 1 || arch.Events[0].Type != "batch" || arch.Events[0].Previous != "1.1.0" || arch.Events[0].Reason != "bump minor" {
+		t.Errorf("events of a = %+v", arch.Events)
+	}
+}
+
+func TestBatchRejected(t *testing.T) {
+	s, done := serve(t)
+	defer done()
+	a, b := s.create("version=1.0.0"), s.create("version=2.0.0")
+
+	tests := []struct {
+		name   string
+		ops    string
+		code   int
+		errors []error
+	}{
+		{
+			"expected version mismatch",
+			fmt.Sprintf(`{"project": %q, "op": "bump"}, {"project": %q, "op": "bump", "expect": "1.9.0"}`, a, b),
+			http.StatusConflict,
+			[]error{nil, ErrVersionMismatch},
+		},
+		{
+			"duplicate operation",
+			fmt.Sprintf(`{"project": %q, "op": "bump"}, {"project": %q, "op": "set", "version": "3.0.0"}`, a, a),
+			http.StatusConflict,
+			[]error{nil, ErrDuplicateOperation},
+		},
+		{
+			"invalid operation",
+			fmt.Sprintf(`{"project": %q, "op": "bump", "type": "huge"}, {"project": %q, "op": "delete"}`, a, b),
+			http.StatusConflict,
+			[]error{ErrInvalidBumpType, ErrInvalidOperation},
+		},
+		{
+			"unknown project",
+			fmt.Sprintf(`{"project": %q, "op": "bump"}, {"project": "00000000-0000-0000-0000-000000000000", "op": "bump"}`, a),
+			http.StatusConflict,
+			[]error{nil, ErrProjectNotFound},
+		},
+	}
+	for _, tt := range tests {
+		code, res := batch(t, s, `{"operations": [`+tt.ops+`]}`)
+		if code != tt.code || res.Applied || res.Error != ErrBatchRejected.Error() || len(res.Results) != len(tt.errors) {
+			t.Errorf("%s: Batch = %d %+v", tt.name, code, res)
+			continue
+		}
+		for i, err := range tt.errors {
+			want := ""
+			if err != nil {
+				want = err.Error()
+			}
+			if res.Results[i].Error != want {
+				t.Errorf("%s: error of operation %d = %q, want %q", tt.name, i, res.Results[i].Error, want)
+			}
+		}
+	}
+	// nothing of a rejected batch is written
+	if got := s.must("GET", "/v1/"+a, nil); got != "1.0.0" {
+		t.Errorf("version of a = %q, want 1.0.0", got)
+	}
+	if got := s.must("GET", "/v1/"+b, nil); got != "2.0.0" {
+		t.Errorf("version of b = %q, want 2.0.0", got)
+	}
+
+	ops := make([]string, maxBatch+1)
+	for i := range ops {
+		ops[i] = fmt.Sprintf(`{"project": %q, "op": "bump"}`, a)
+	}
+	for body, want := range map[string]error{
+		`{"operations": []}`: ErrInvalidBatch,
+		`{"operations": `:    ErrInvalidBatch,
+		`{"operations": [` + strings.Join(ops, ", ") + `]}`: ErrBatchTooLarge,
+	} {
+		if code, out := s.send("POST", "/v1/batch", "application/json", body); code != http.StatusForbidden || out != want.Error() {
+			t.Errorf("Batch(%.40s) = %d %q, want %v", body, code, out, want)
+		}
+	}
+
+	// every project of a batch is authorized
+	s.r.Authorize(func(c *gin.Context, project, access string) bool {
+		return project == a && access == "write"
+	})
+	body := fmt.Sprintf(`{"operations": [{"project": %q, "op": "bump"}, {"project": %q, "op": "bump"}]}`, a, b)
+	if code, out := s.send("POST", "/v1/batch", "application/json", body); code != http.StatusForbidden || out != ErrAccessDenied.Error() {
+		t.Errorf("Batch of a denied project = %d %q", code, out)
+	}
+}
@@ -0,0 +1,185 @@
+package v1
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/gin-gonic/gin"
+	"github.com/samuelngs/semver/backend"
+)
+
+// valid release channel name, e.g. stable, beta or nightly
+var channelName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// channel returns the key directories of a release channel, the project's
+// default stream is returned when name is empty
+func (r *Router) channel(name string) ([]string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+	if !channelName.MatchString(name) {
+		return nil, ErrInvalidChannel
+	}
+	return []string{"channels", name}, nil
+}
+
+// key creates a key of project `id` under the channel scope
+func (r *Router) key(id string, scope []string, dirs ...string) *backend.Key {
+	path := make([]string, 0, len(scope)+len(dirs))
+	path = append(path, scope...)
+	path = append(path, dirs...)
+	return r.m.Path(id, path...)
+}
+
+// current returns the current version of project `id` under the component
+// and channel scope
+func (r *Router) current(id string, scope []string) (semver.Version, error) {
+	var ver semver.Version
+	if deleted, err := r.deleted(id); err != nil {
+		return ver, err
+	} else if deleted {
+		return ver, ErrProjectNotFound
+	}
+	vers, err := r.m.Get(
+		r.key(id, scope, "version"),
+	)
+	if err != nil || len(vers) <= 0 || vers[0] == "" {
+		if len(scope) > 0 {
+			if exists, err := r.exists(id); err == nil && exists {
+				if scope[0] == "components" && (len(scope) == 2 || !r.found(id, scope[:2])) {
+					return ver, ErrComponentNotFound
+				}
+				return ver, ErrChannelNotFound
+			}
+		}
+		return ver, ErrProjectNotFound
+	}
+	return r.version(vers[0])
+}
+
+// found checks if the scope of project `id` has a current version
+func (r *Router) found(id string, scope []string) bool {
+	vers, err := r.m.Get(r.key(id, scope, "version"))
+	return err == nil && len(vers) > 0 && vers[0] != ""
+}
+
+// Channels lists release channels of project `id`
+func (r *Router) Channels(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	comp, err := r.component(c.Param("name"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if _, err := r.current(id, comp); err != nil {
+		r.err(c, err)
+		return
+	}
+	keys, err := r.m.List(r.key(id, comp, "channels"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	names := []string{}
+	n := len(comp)
+	for _, key := range keys {
+		if len(key.Dirs) == n+3 && key.Dirs[n] == "channels" && key.Dirs[n+2] == "version" {
+			names = append(names, key.Dirs[n+1])
+		}
+	}
+	sort.Strings(names)
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	list := &Channels{
+		Channels: make([]*Channel, 0, len(names)),
+	}
+	for _, name := range names {
+		ver, err := r.current(id, append(comp, "channels", name))
+		if err != nil {
+			r.err(c, err)
+			return
+		}
+		list.Channels = append(list.Channels, &Channel{
+			Name:    name,
+			Version: sc.format(ver),
+		})
+	}
+	r.echo(c, list)
+}
+
+// Promote copies a version from one release channel to another, the event
+// is recorded in the history of the target channel
+func (r *Router) Promote(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	comp, err := r.component(c.Param("name"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	from, err := r.channel(c.PostForm("from"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	to, err := r.channel(c.PostForm("to"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	from, to = append(comp, from...), append(comp, to...)
+	cur, err := r.current(id, from)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	ver := cur
+	if s := strings.TrimSpace(c.PostForm("version")); s != "" {
+		if ver, err = sc.parse(s); err != nil {
+			r.err(c, err)
+			return
+		}
+		if !r.archived(id, from, ver) {
+			r.err(c, ErrVersionNotFound)
+			return
+		}
+	}
+	ev := &Event{Type: "promote", From: strings.TrimSpace(c.PostForm("from")), Version: sc.format(ver)}
+	if prev, err := r.current(id, to); err == nil {
+		ev.Previous = sc.format(prev)
+	}
+	if err := r.m.Set(
+		ver.String(),
+		r.key(id, to, "version"),
+		r.key(id, to, "archive", ver.String()),
+	); err != nil {
+		r.err(c, err)
+		return
+	}
+	if err := r.record(id, to, ev); err != nil {
+		r.err(c, err)
+		return
+	}
+	res := r.versioning(sc, ver)
+	r.echo(c, res)
+}
@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestChannels(t *testing.T) {
+	s, done := serve(t)
+	defer done()
+	id := s.create("version=1.0.0")
+	p := "/v1/" + id
+
+	if got := s.must("POST", p+"?channel=beta", url.Values{"version": {"2.0.0-beta.1"}}); got != "2.0.0-beta.1" {
+		t.Errorf("Set beta = %q", got)
+	}
+	if got := s.must("GET", p, nil); got != "1.0.0" {
+		t.Errorf("default stream = %q, want 1.0.0", got)
+	}
+	if got := s.must("GET", p+"/bump?channel=beta&type=patch", nil); got != "2.0.1" {
+		t.Errorf("Bump beta = %q, want 2.0.1", got)
+	}
+	if code, body := s.do("GET", p+"?channel=stable", nil); code != http.StatusForbidden || body != ErrChannelNotFound.Error() {
+		t.Errorf("Get of a missing channel = %d %q", code, body)
+	}
+	if code, body := s.do("GET", p+"?channel=Beta!", nil); code != http.StatusForbidden || body != ErrInvalidChannel.Error() {
+		t.Errorf("Get of an invalid channel = %d %q", code, body)
+	}
+
+	if got := s.must("POST", p+"/promote", url.Values{"from": {"beta"}, "to": {"stable"}}); got != "2.0.1" {
+		t.Errorf("Promote = %q, want 2.0.1", got)
+	}
+	if got := s.must("POST", p+"/promote", url.Values{"from": {"beta"}, "version": {"2.0.0-beta.1"}}); got != "2.0.0-beta.1" {
+		t.Errorf("Promote of an archived version = %q", got)
+	}
+	if code, body := s.do("POST", p+"/promote", url.Values{"from": {"beta"}, "to": {"stable"}, "version": {"1.0.0"}}); code != http.StatusForbidden || body != ErrVersionNotFound.Error() {
+		t.Errorf("Promote of a version missing from the channel = %d %q", code, body)
+	}
+	if got := s.must("GET", p, nil); got != "2.0.0-beta.1" {
+		t.Errorf("default stream after promote = %q", got)
+	}
+	if got, want := s.must("GET", p+"/channels", nil), "beta 2.0.1\nstable 2.0.1"; got != want {
+		t.Errorf("Channels = %q, want %q", got, want)
+	}
+
+	var arch Archive
+	s.json("GET", p+"/history?channel=stable", nil, &arch)
+	if len(arch.Versions) != 1 || arch.Versions[0].Version != "2.0.1" {
+		t.Errorf("stable history = %+v", arch.Versions)
+	}
+	if len(arch.Events) != 1 || arch.Events[0].Type != "promote" || arch.Events[0].From != "beta" || arch.Events[0].Version != "2.0.1" {
+		t.Errorf("stable events = %+v", arch.Events)
+	}
+}
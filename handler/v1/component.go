@@ -0,0 +1,235 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/gin-gonic/gin"
+)
+
+// valid component name, e.g. api, web or cli-tools
+var componentName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// component returns the key directories of a component, the project itself
+// is returned when name is empty
+func (r *Router) component(name string) ([]string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+	if !componentName.MatchString(name) {
+		return nil, ErrInvalidComponent
+	}
+	return []string{"components", name}, nil
+}
+
+// scope returns the key directories of the component and the release channel
+// of a request, e.g. /v1/{id}/components/{name}?channel=beta
+func (r *Router) scope(c *gin.Context) ([]string, error) {
+	comp, err := r.component(c.Param("name"))
+	if err != nil {
+		return nil, err
+	}
+	ch, err := r.channel(c.Query("channel"))
+	if err != nil {
+		return nil, err
+	}
+	return append(comp, ch...), nil
+}
+
+// components returns the sorted component names of project `id`
+func (r *Router) components(id string) ([]string, error) {
+	keys, err := r.m.List(r.m.Path(id, "components"))
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, key := range keys {
+		if len(key.Dirs) == 3 && key.Dirs[0] == "components" && key.Dirs[2] == "version" {
+			names = append(names, key.Dirs[1])
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Components is the aggregate view of the components of project `id`
+func (r *Router) Components(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	ver, err := r.current(id, nil)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	names, err := r.components(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	list := &Components{
+		Version:    sc.format(ver),
+		Components: make([]*Component, 0, len(names)),
+	}
+	for _, name := range names {
+		cur, err := r.current(id, []string{"components", name})
+		if err != nil {
+			r.err(c, err)
+			return
+		}
+		list.Components = append(list.Components, &Component{
+			Name:    name,
+			Version: sc.format(cur),
+		})
+	}
+	r.echo(c, list)
+}
+
+// DeleteComponent removes a component and its history from project `id`
+func (r *Router) DeleteComponent(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	scope, err := r.component(c.Param("name"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if _, err := r.current(id, scope); err != nil {
+		r.err(c, err)
+		return
+	}
+	keys, err := r.m.List(r.key(id, scope))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	// the listing is a prefix match, keep only the records of this component
+	matched := keys[:0]
+	for _, key := range keys {
+		if len(key.Dirs) > 2 && key.Dirs[0] == scope[0] && key.Dirs[1] == scope[1] {
+			matched = append(matched, key)
+		}
+	}
+	if err := r.m.Delete(matched...); err != nil {
+		r.err(c, err)
+		return
+	}
+	c.String(http.StatusOK, "ok")
+}
+
+// ReleaseComponents bumps several components of project `id` at once, the
+// bump types are given as `components=api:minor` form values or as a json
+// object {"components": {"api": "minor"}}, the type defaults to patch
+func (r *Router) ReleaseComponents(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	types := make(map[string]string)
+	if strings.HasPrefix(c.ContentType(), "application/json") {
+		var body struct {
+			Components map[string]string `json:"components"`
+		}
+		if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil {
+			r.err(c, ErrInvalidRequestBody)
+			return
+		}
+		types = body.Components
+	} else {
+		form, err := r.form(c)
+		if err != nil {
+			r.err(c, err)
+			return
+		}
+		for _, s := range form["components"] {
+			parts := strings.SplitN(s, ":", 2)
+			if len(parts) == 1 {
+				parts = append(parts, "patch")
+			}
+			types[parts[0]] = parts[1]
+		}
+	}
+	if len(types) == 0 {
+		r.err(c, ErrComponentNotFound)
+		return
+	}
+	ver, err := r.current(id, nil)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	ch, err := r.channel(c.Query("channel"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	// validate every component before bumping any of them
+	names := make([]string, 0, len(types))
+	curs := make(map[string]semver.Version, len(types))
+	for name, typ := range types {
+		scope, err := r.component(name)
+		if err != nil || scope == nil {
+			r.err(c, ErrInvalidComponent)
+			return
+		}
+		if typ != "major" && typ != "minor" && typ != "patch" {
+			r.err(c, ErrInvalidBumpType)
+			return
+		}
+		cur, err := r.current(id, append(scope, ch...))
+		if err != nil {
+			r.err(c, err)
+			return
+		}
+		curs[name] = cur
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	list := &Components{
+		Version:    sc.format(ver),
+		Components: make([]*Component, 0, len(names)),
+	}
+	notes := make([]string, 0, len(names))
+	for _, name := range names {
+		bumped, err := r.bumpFrom(id, sc, append([]string{"components", name}, ch...), curs[name], types[name])
+		if err != nil {
+			r.err(c, err)
+			return
+		}
+		list.Components = append(list.Components, &Component{
+			Name:    name,
+			Version: sc.format(bumped),
+		})
+		notes = append(notes, fmt.Sprintf("%s %s", name, sc.format(bumped)))
+	}
+	if err := r.record(id, ch, &Event{Type: "release", Reason: strings.Join(notes, ", ")}); err != nil {
+		r.err(c, err)
+		return
+	}
+	r.echo(c, list)
+}
@@ -0,0 +1,67 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestComponents(t *testing.T) {
+	s, done := serve(t)
+	defer done()
+	id := s.create("version=3.0.0")
+	p := "/v1/" + id
+
+	if code, body := s.do("GET", p+"/components/api", nil); code != http.StatusForbidden || body != ErrComponentNotFound.Error() {
+		t.Errorf("Get of a missing component = %d %q", code, body)
+	}
+	s.must("POST", p+"/components/api", url.Values{"version": {"1.0.0"}})
+	s.must("POST", p+"/components/api2", url.Values{"version": {"0.1.0"}})
+	s.must("POST", p+"/components/web", url.Values{"version": {"0.3.0"}})
+	if got := s.must("GET", p+"/components/api/bump?type=minor", nil); got != "1.1.0" {
+		t.Errorf("component Bump = %q, want 1.1.0", got)
+	}
+	if got := s.must("GET", p, nil); got != "3.0.0" {
+		t.Errorf("project version = %q, want 3.0.0", got)
+	}
+	if got, want := s.must("GET", p+"/components/api/history", nil), "1.0.0\n1.1.0"; got != want {
+		t.Errorf("component History = %q, want %q", got, want)
+	}
+
+	if got, want := s.must("POST", p+"/release", url.Values{"components": {"api:major", "web"}}), "api 2.0.0\nweb 0.3.1"; got != want {
+		t.Errorf("Release = %q, want %q", got, want)
+	}
+	if code, body := s.send("POST", p+"/release", "application/json", `{"components": {"web": "minor"}}`); code != http.StatusOK || body != "web 0.4.0" {
+		t.Errorf("json Release = %d %q", code, body)
+	}
+
+	// a release is validated before any component is bumped
+	tests := []struct {
+		form url.Values
+		want error
+	}{
+		{url.Values{"components": {"api:minor", "web:huge"}}, ErrInvalidBumpType},
+		{url.Values{"components": {"api:minor", "docs"}}, ErrComponentNotFound},
+		{url.Values{"components": {"api:minor", "Docs!"}}, ErrInvalidComponent},
+		{url.Values{}, ErrComponentNotFound},
+	}
+	for _, tt := range tests {
+		if code, body := s.do("POST", p+"/release", tt.form); code != http.StatusForbidden || body != tt.want.Error() {
+			t.Errorf("Release(%v) = %d %q, want %v", tt.form, code, body, tt.want)
+		}
+	}
+	if code, body := s.send("POST", p+"/release", "application/json", `{"components": [`); code != http.StatusForbidden || body != ErrInvalidRequestBody.Error() {
+		t.Errorf("Release of an invalid body = %d %q", code, body)
+	}
+	if got, want := s.must("GET", p+"/components", nil), "api 2.0.0\napi2 0.1.0\nweb 0.4.0"; got != want {
+		t.Errorf("Components = %q, want %q", got, want)
+	}
+
+	// deleting a component keeps the components sharing its name prefix
+	if got := s.must("DELETE", p+"/components/api", nil); got != "ok" {
+		t.Errorf("DeleteComponent = %q", got)
+	}
+	if got, want := s.must("GET", p+"/components", nil), "api2 0.1.0\nweb 0.4.0"; got != want {
+		t.Errorf("Components after delete = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package v1
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// list of conventional commit bump levels, ordered by precedence
+const (
+	levelNone = iota
+	levelPatch
+	levelMinor
+	levelMajor
+)
+
+// conventional commit header: type(scope)!: description
+var conventionalHeader = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+
+// conventional commit breaking change footer
+var conventionalBreaking = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:\s*(.+)$`)
+
+// changelog section titles of well-known commit types, in display order
+var conventionalTitles = []struct {
+	Type  string
+	Title string
+}{
+	{"breaking", "Breaking Changes"},
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance Improvements"},
+	{"revert", "Reverts"},
+	{"refactor", "Code Refactoring"},
+	{"docs", "Documentation"},
+	{"style", "Styles"},
+	{"test", "Tests"},
+	{"build", "Build System"},
+	{"ci", "Continuous Integration"},
+	{"chore", "Chores"},
+}
+
+// Commit represents a parsed conventional commit message
+type Commit struct {
+	Type        string
+	Scope       string
+	Description string
+	Breaking    string
+}
+
+// parseCommit parses a commit message according to the conventional commits
+// specification, it returns nil when the message does not follow the format
+func parseCommit(msg string) *Commit {
+	msg = strings.TrimSpace(msg)
+	lines := strings.SplitN(msg, "\n", 2)
+	m := conventionalHeader.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if m == nil {
+		return nil
+	}
+	commit := &Commit{
+		Type:        strings.ToLower(m[1]),
+		Scope:       m[2],
+		Description: strings.TrimSpace(m[4]),
+	}
+	if m[3] == "!" {
+		commit.Breaking = commit.Description
+	}
+	if len(lines) > 1 {
+		if b := conventionalBreaking.FindStringSubmatch(lines[1]); b != nil {
+			commit.Breaking = strings.TrimSpace(b[1])
+		}
+	}
+	return commit
+}
+
+// level returns the bump level implied by the commit
+func (c *Commit) level() int {
+	switch {
+	case c.Breaking != "":
+		return levelMajor
+	case c.Type == "feat":
+		return levelMinor
+	case c.Type == "fix":
+		return levelPatch
+	}
+	return levelNone
+}
+
+// note returns the changelog line of the commit
+func (c *Commit) note() string {
+	if c.Scope != "" {
+		return "**" + c.Scope + ":** " + c.Description
+	}
+	return c.Description
+}
+
+// conventional parses a list of commit messages and returns the bump type
+// {major, minor, patch} together with the changelog grouped by commit type.
+// During initial development (semver 0.y.z) breaking changes bump minor.
+func conventional(initial bool, msgs []string) (string, []*ChangelogSection) {
+	level := levelNone
+	groups := make(map[string][]string)
+	for _, msg := range msgs {
+		commit := parseCommit(msg)
+		if commit == nil {
+			continue
+		}
+		if l := commit.level(); l > level {
+			level = l
+		}
+		if commit.Breaking != "" {
+			groups["breaking"] = append(groups["breaking"], commit.Breaking)
+		}
+		groups[commit.Type] = append(groups[commit.Type], commit.note())
+	}
+	if level == levelMajor && initial {
+		level = levelMinor
+	}
+	sections := []*ChangelogSection{}
+	for _, t := range conventionalTitles {
+		if notes, ok := groups[t.Type]; ok {
+			sections = append(sections, &ChangelogSection{Type: t.Type, Title: t.Title, Notes: notes})
+			delete(groups, t.Type)
+		}
+	}
+	others := make([]string, 0, len(groups))
+	for typ := range groups {
+		others = append(others, typ)
+	}
+	sort.Strings(others)
+	for _, typ := range others {
+		sections = append(sections, &ChangelogSection{Type: typ, Title: typ, Notes: groups[typ]})
+	}
+	switch level {
+	case levelMajor:
+		return "major", sections
+	case levelMinor:
+		return "minor", sections
+	case levelPatch:
+		return "patch", sections
+	}
+	return "", sections
+}
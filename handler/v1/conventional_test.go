@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCommit(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want *Commit
+	}{
+		{"feat: add login", &Commit{Type: "feat", Description: "add login"}},
+		{"Fix(api): handle nil body", &Commit{Type: "fix", Scope: "api", Description: "handle nil body"}},
+		{"feat(cli)!: drop --legacy", &Commit{Type: "feat", Scope: "cli", Description: "drop --legacy", Breaking: "drop --legacy"}},
+		{"refactor!: rename packages", &Commit{Type: "refactor", Description: "rename packages", Breaking: "rename packages"}},
+		{"  chore: trim  \n", &Commit{Type: "chore", Description: "trim"}},
+		{
+			"feat: new config\n\nbody text\n\nBREAKING CHANGE: config moved to yaml",
+			&Commit{Type: "feat", Description: "new config", Breaking: "config moved to yaml"},
+		},
+		{
+			"fix: parser\n\nBREAKING-CHANGE: stricter input",
+			&Commit{Type: "fix", Description: "parser", Breaking: "stricter input"},
+		},
+		{"fix: parser\n\nnot a BREAKING CHANGE: footer", &Commit{Type: "fix", Description: "parser"}},
+		{"Merge branch 'master'", nil},
+		{"feat add login", nil},
+		{"feat(: broken scope", nil},
+		{"feat:", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := parseCommit(tt.msg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCommit(%q) = %+v, want %+v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestConventional(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial bool
+		msgs    []string
+		typ     string
+		types   []string
+	}{
+		{"empty", false, nil, "", []string{}},
+		{"no releasable changes", false, []string{"docs: readme", "chore: deps"}, "", []string{"docs", "chore"}},
+		{"not conventional", false, []string{"update stuff"}, "", []string{}},
+		{"fix", false, []string{"fix: a", "docs: b"}, "patch", []string{"fix", "docs"}},
+		{"feat over fix", false, []string{"fix: a", "feat: b"}, "minor", []string{"feat", "fix"}},
+		{"breaking", false, []string{"feat: a", "fix!: b"}, "major", []string{"breaking", "feat", "fix"}},
+		{"breaking footer", false, []string{"chore: a\n\nBREAKING CHANGE: b"}, "major", []string{"breaking", "chore"}},
+		{"breaking before 1.0", true, []string{"feat!: a"}, "minor", []string{"breaking", "feat"}},
+		{"unknown types sorted last", false, []string{"wip: a", "deps: b", "fix: c"}, "patch", []string{"fix", "deps", "wip"}},
+	}
+	for _, tt := range tests {
+		typ, sections := conventional(tt.initial, tt.msgs)
+		if typ != tt.typ {
+			t.Errorf("%s: type = %q, want %q", tt.name, typ, tt.typ)
+		}
+		types := []string{}
+		for _, s := range sections {
+			types = append(types, s.Type)
+		}
+		if !reflect.DeepEqual(types, tt.types) {
+			t.Errorf("%s: sections = %v, want %v", tt.name, types, tt.types)
+		}
+	}
+}
+
+func TestConventionalNotes(t *testing.T) {
+	_, sections := conventional(false, []string{
+		"feat(api): add search",
+		"feat: add export",
+		"fix!: drop v0 endpoints",
+	})
+	want := []*ChangelogSection{
+		{Type: "breaking", Title: "Breaking Changes", Notes: []string{"drop v0 endpoints"}},
+		{Type: "feat", Title: "Features", Notes: []string{"**api:** add search", "add export"}},
+		{Type: "fix", Title: "Bug Fixes", Notes: []string{"drop v0 endpoints"}},
+	}
+	if !reflect.DeepEqual(sections, want) {
+		t.Errorf("sections = %+v, want %+v", sections, want)
+	}
+}
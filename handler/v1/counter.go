@@ -0,0 +1,267 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/gin-gonic/gin"
+)
+
+// valid counter name, e.g. build, android or ios
+var counterName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// counter reset policies
+const (
+	resetNever = "never"
+	resetMajor = "major"
+	resetMinor = "minor"
+)
+
+// counter validates a counter name
+func (r *Router) counter(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if !counterName.MatchString(name) {
+		return "", ErrInvalidCounter
+	}
+	return name, nil
+}
+
+// epoch returns the period of a counter value, the value starts again from
+// 1 in every new epoch, e.g. 2 for major and 2.3 for minor resets of 2.3.1
+func epoch(reset string, ver semver.Version) string {
+	switch reset {
+	case resetMajor:
+		return strconv.FormatUint(ver.Major, 10)
+	case resetMinor:
+		return fmt.Sprintf("%d.%d", ver.Major, ver.Minor)
+	}
+	return "all"
+}
+
+// policy returns the reset policy of counter `name` of project `id`, an
+// empty policy means the counter does not exist
+func (r *Router) policy(id, name string) (string, error) {
+	vals, err := r.m.Get(r.m.Path(id, "counters", name, "reset"))
+	if err != nil || len(vals) == 0 {
+		return "", err
+	}
+	return vals[0], nil
+}
+
+// value returns the value of counter `name` of project `id` in the epoch
+// of version ver
+func (r *Router) value(id, name, reset string, ver semver.Version) (int64, error) {
+	vals, err := r.m.Get(r.m.Path(id, "counters", name, "values", epoch(reset, ver)))
+	if err != nil || len(vals) == 0 || vals[0] == "" {
+		return 0, err
+	}
+	return strconv.ParseInt(vals[0], 10, 64)
+}
+
+// counters returns the sorted counter names of project `id`
+func (r *Router) counters(id string) ([]string, error) {
+	keys, err := r.m.List(r.m.Path(id, "counters"))
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, key := range keys {
+		if len(key.Dirs) == 3 && key.Dirs[0] == "counters" && key.Dirs[2] == "reset" {
+			names = append(names, key.Dirs[1])
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Counters lists the counters of project `id` with their current values
+func (r *Router) Counters(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	ver, err := r.current(id, nil)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	names, err := r.counters(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	list := &Counters{Counters: make([]*Counter, 0, len(names))}
+	for _, name := range names {
+		reset, err := r.policy(id, name)
+		if err != nil {
+			r.err(c, err)
+			return
+		}
+		n, err := r.value(id, name, reset, ver)
+		if err != nil {
+			r.err(c, err)
+			return
+		}
+		list.Counters = append(list.Counters, &Counter{
+			Name:    name,
+			Reset:   reset,
+			Value:   n,
+			Version: sc.format(ver),
+		})
+	}
+	r.echo(c, list)
+}
+
+// GetCounter returns the value of a counter of project `id` for the current
+// version
+func (r *Router) GetCounter(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	name, err := r.counter(c.Param("counter"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	ver, err := r.current(id, nil)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	reset, err := r.policy(id, name)
+	if err != nil {
+		r.err(c, err)
+		return
+	} else if reset == "" {
+		r.err(c, ErrCounterNotFound)
+		return
+	}
+	n, err := r.value(id, name, reset, ver)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	r.echo(c, &Counter{Name: name, Reset: reset, Value: n, Version: sc.format(ver)})
+}
+
+// Increment increases a counter of project `id` by one and returns the new
+// value, the counter is created on first use. The `reset` form value sets
+// whether the counter starts again on a new major or minor version.
+func (r *Router) Increment(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	name, err := r.counter(c.Param("counter"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	ver, err := r.current(id, nil)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	reset, err := r.policy(id, name)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if s := strings.TrimSpace(c.PostForm("reset")); s != "" && s != reset {
+		if s != resetNever && s != resetMajor && s != resetMinor {
+			r.err(c, ErrInvalidCounterReset)
+			return
+		}
+		reset = s
+		if err := r.m.Set(reset, r.m.Path(id, "counters", name, "reset")); err != nil {
+			r.err(c, err)
+			return
+		}
+	} else if reset == "" {
+		reset = resetNever
+		if err := r.m.Set(reset, r.m.Path(id, "counters", name, "reset")); err != nil {
+			r.err(c, err)
+			return
+		}
+	}
+	n, err := r.m.Incr(r.m.Path(id, "counters", name, "values", epoch(reset, ver)), 1)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if err := r.record(id, nil, &Event{Type: "counter", Counter: name, Value: n, Version: sc.format(ver)}); err != nil {
+		r.err(c, err)
+		return
+	}
+	r.echo(c, &Counter{Name: name, Reset: reset, Value: n, Version: sc.format(ver)})
+}
+
+// DeleteCounter removes a counter of project `id` with its values
+func (r *Router) DeleteCounter(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	name, err := r.counter(c.Param("counter"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if _, err := r.current(id, nil); err != nil {
+		r.err(c, err)
+		return
+	}
+	if reset, err := r.policy(id, name); err != nil {
+		r.err(c, err)
+		return
+	} else if reset == "" {
+		r.err(c, ErrCounterNotFound)
+		return
+	}
+	keys, err := r.m.List(r.m.Path(id, "counters", name))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	// the listing is a prefix match, keep only the records of this counter
+	matched := keys[:0]
+	for _, key := range keys {
+		if len(key.Dirs) > 2 && key.Dirs[0] == "counters" && key.Dirs[1] == name {
+			matched = append(matched, key)
+		}
+	}
+	if err := r.m.Delete(matched...); err != nil {
+		r.err(c, err)
+		return
+	}
+	c.String(http.StatusOK, "ok")
+}
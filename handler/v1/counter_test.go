@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestCounters(t *testing.T) {
+	s, done := serve(t)
+	defer done()
+	id := s.create("version=1.0.0")
+	p := "/v1/" + id
+	incr := func(name, reset string) string {
+		var form url.Values
+		if reset != "" {
+			form = url.Values{"reset": {reset}}
+		}
+		return s.must("POST", p+"/counters/"+name, form)
+	}
+
+	tests := []struct {
+		bump string
+		want map[string]string
+	}{
+		{"", map[string]string{"android": "1", "ios": "1", "build": "1"}},
+		{"", map[string]string{"android": "2", "ios": "2", "build": "2"}},
+		{"patch", map[string]string{"android": "3", "ios": "3", "build": "3"}},
+		{"minor", map[string]string{"android": "4", "ios": "1", "build": "4"}},
+		{"major", map[string]string{"android": "5", "ios": "1", "build": "1"}},
+	}
+	resets := map[string]string{"android": "", "ios": "minor", "build": "major"}
+	for i, tt := range tests {
+		if tt.bump != "" {
+			s.must("GET", p+"/bump?type="+tt.bump, nil)
+		}
+		for _, name := range []string{"android", "ios", "build"} {
+			reset := resets[name]
+			if i > 0 {
+				// the reset policy is kept between increments
+				reset = ""
+			}
+			if got := incr(name, reset); got != tt.want[name] {
+				t.Errorf("step %d: %s = %s, want %s", i, name, got, tt.want[name])
+			}
+		}
+	}
+	if got, want := s.must("GET", p+"/counters", nil), "android 5\nbuild 1\nios 1"; got != want {
+		t.Errorf("Counters = %q, want %q", got, want)
+	}
+	if got := s.must("GET", p+"/counters/android", nil); got != "5" {
+		t.Errorf("GetCounter = %q, want 5", got)
+	}
+
+	// switching the policy starts a new epoch
+	if got := incr("android", "major"); got != "1" {
+		t.Errorf("android after switching to major resets = %s, want 1", got)
+	}
+
+	var arch Archive
+	s.json("GET", p+"/history", nil, &arch)
+	issued := make(map[string]map[string]int64)
+	for _, v := range arch.Versions {
+		issued[v.Version] = v.Counters
+	}
+	if want := map[string]int64{"android": 4, "ios": 1, "build": 4}; !reflect.DeepEqual(issued["1.1.0"], want) {
+		t.Errorf("counters of 1.1.0 = %v, want %v", issued["1.1.0"], want)
+	}
+
+	errs := []struct {
+		method string
+		path   string
+		form   url.Values
+		want   error
+	}{
+		{"POST", p + "/counters/ios", url.Values{"reset": {"sometimes"}}, ErrInvalidCounterReset},
+		{"POST", p + "/counters/iOS!", nil, ErrInvalidCounter},
+		{"GET", p + "/counters/web", nil, ErrCounterNotFound},
+		{"DELETE", p + "/counters/web", nil, ErrCounterNotFound},
+	}
+	for _, tt := range errs {
+		if code, body := s.do(tt.method, tt.path, tt.form); code != http.StatusForbidden || body != tt.want.Error() {
+			t.Errorf("%s %s = %d %q, want %v", tt.method, tt.path, code, body, tt.want)
+		}
+	}
+
+	// deleting a counter keeps the counters sharing its name prefix
+	incr("build2", "")
+	if got := s.must("DELETE", p+"/counters/build", nil); got != "ok" {
+		t.Errorf("DeleteCounter = %q", got)
+	}
+	if got, want := s.must("GET", p+"/counters", nil), "android 1\nbuild2 1\nios 1"; got != want {
+		t.Errorf("Counters after delete = %q, want %q", got, want)
+	}
+	if got := incr("build", ""); got != "1" {
+		t.Errorf("build after delete = %s, want 1", got)
+	}
+}
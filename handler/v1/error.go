@@ -1,12 +1,41 @@
 package v1
 
-import "errors"
+import "github.com/samuelngs/semver/pkg/api"
 
-// List of error messages
+// List of error messages, the messages are defined in pkg/api
 var (
-	ErrProjectNotFound         = errors.New("project id does not match any records in our database")
-	ErrInvalidVersioningFormat = errors.New("invalid semantic versioning format")
-	ErrInvalidUUID             = errors.New("invalid uuid")
-
-	ErrInternalServer = errors.New("internal server error")
+	ErrProjectNotFound         = api.ErrProjectNotFound
+	ErrInvalidVersioningFormat = api.ErrInvalidVersioningFormat
+	ErrInvalidUUID             = api.ErrInvalidUUID
+	ErrNoReleasableChanges     = api.ErrNoReleasableChanges
+	ErrInvalidRequestBody      = api.ErrInvalidRequestBody
+	ErrInvalidChannel          = api.ErrInvalidChannel
+	ErrChannelNotFound         = api.ErrChannelNotFound
+	ErrVersionNotFound         = api.ErrVersionNotFound
+	ErrInvalidTag              = api.ErrInvalidTag
+	ErrInvalidRange            = api.ErrInvalidRange
+	ErrTagNotFound             = api.ErrTagNotFound
+	ErrInvalidComponent        = api.ErrInvalidComponent
+	ErrComponentNotFound       = api.ErrComponentNotFound
+	ErrInvalidBumpType         = api.ErrInvalidBumpType
+	ErrInvalidBatch            = api.ErrInvalidBatch
+	ErrBatchTooLarge           = api.ErrBatchTooLarge
+	ErrInvalidOperation        = api.ErrInvalidOperation
+	ErrDuplicateOperation      = api.ErrDuplicateOperation
+	ErrVersionMismatch         = api.ErrVersionMismatch
+	ErrBatchRejected           = api.ErrBatchRejected
+	ErrInvalidScheme           = api.ErrInvalidScheme
+	ErrInvalidCalverFormat     = api.ErrInvalidCalverFormat
+	ErrCalendarPeriodReleased  = api.ErrCalendarPeriodReleased
+	ErrInvalidCounter          = api.ErrInvalidCounter
+	ErrCounterNotFound         = api.ErrCounterNotFound
+	ErrInvalidCounterReset     = api.ErrInvalidCounterReset
+	ErrInvalidTTL              = api.ErrInvalidTTL
+	ErrVersionReserved         = api.ErrVersionReserved
+	ErrVersionReleased         = api.ErrVersionReleased
+	ErrReservationNotFound     = api.ErrReservationNotFound
+	ErrInvalidReservationToken = api.ErrInvalidReservationToken
+	ErrForbidden               = api.ErrForbidden
+	ErrAccessDenied            = api.ErrAccessDenied
+	ErrInternalServer          = api.ErrInternalServer
 )
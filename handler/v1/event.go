@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// record appends an event to the history of project `id` under the channel scope
+func (r *Router) record(id string, scope []string, ev *Event) error {
+	now := time.Now().UTC()
+	ev.Time = now.Format(time.RFC3339)
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return r.m.Set(
+		string(b[:]),
+		r.key(id, scope, "events", strconv.FormatInt(now.UnixNano(), 10)),
+	)
+}
+
+// events returns the recorded events of project `id` under the channel scope
+func (r *Router) events(id string, scope []string) ([]*Event, error) {
+	keys, err := r.m.List(r.key(id, scope, "events"))
+	if err != nil {
+		return nil, err
+	}
+	vals, err := r.m.Get(keys...)
+	if err != nil {
+		return nil, err
+	}
+	evs := make([]*Event, 0, len(vals))
+	for _, s := range vals {
+		var ev *Event
+		if err := json.Unmarshal([]byte(s), &ev); err != nil || ev == nil {
+			continue
+		}
+		evs = append(evs, ev)
+	}
+	return evs, nil
+}
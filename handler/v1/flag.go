@@ -0,0 +1,270 @@
+package v1
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/gin-gonic/gin"
+)
+
+// Flags represents the yank and deprecation state of an archived version
+type Flags struct {
+	Yanked          bool   `json:"yanked,omitempty"`
+	YankReason      string `json:"yank_reason,omitempty"`
+	Deprecated      bool   `json:"deprecated,omitempty"`
+	DeprecateReason string `json:"deprecate_reason,omitempty"`
+
+	// Reason is the shared reason of flags written before the reasons were
+	// kept apart, it is moved to the reason of each set flag when read
+	Reason string `json:"reason,omitempty"`
+}
+
+// apply copies the flags to a version
+func (f *Flags) apply(v *Versioning) {
+	v.Yanked, v.YankReason = f.Yanked, f.YankReason
+	v.Deprecated, v.DeprecateReason = f.Deprecated, f.DeprecateReason
+}
+
+// flags returns the flags of archived versions of project `id` keyed by version
+func (r *Router) flags(id string, scope []string) (map[string]*Flags, error) {
+	keys, err := r.m.List(r.key(id, scope, "flags"))
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]*Flags)
+	for _, key := range keys {
+		vals, err := r.m.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		var f *Flags
+		if len(vals) <= 0 || json.Unmarshal([]byte(vals[0]), &f) != nil || f == nil {
+			continue
+		}
+		if f.Reason != "" {
+			if f.Yanked && f.YankReason == "" {
+				f.YankReason = f.Reason
+			}
+			if f.Deprecated && f.DeprecateReason == "" {
+				f.DeprecateReason = f.Reason
+			}
+			f.Reason = ""
+		}
+		res[key.Dirs[len(key.Dirs)-1]] = f
+	}
+	return res, nil
+}
+
+// archive returns the archived versions of project `id` under the channel scope
+func (r *Router) archive(id string, scope []string) ([]semver.Version, error) {
+	keys, err := r.m.List(r.key(id, scope, "archive"))
+	if err != nil {
+		return nil, err
+	}
+	vals, err := r.m.Get(keys...)
+	if err != nil {
+		return nil, err
+	}
+	vers := make([]semver.Version, len(vals))
+	for i, s := range vals {
+		if vers[i], err = r.version(s); err != nil {
+			return nil, err
+		}
+	}
+	semver.Sort(vers)
+	return vers, nil
+}
+
+// flag updates the flags of an archived version
+func (r *Router) flag(c *gin.Context, typ string, set bool) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	scope, err := r.scope(c)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if _, err := r.current(id, scope); err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	ver, err := sc.parse(c.Param("version"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if !r.archived(id, scope, ver) {
+		r.err(c, ErrVersionNotFound)
+		return
+	}
+	all, err := r.flags(id, scope)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	f, ok := all[ver.String()]
+	if !ok {
+		f = new(Flags)
+	}
+	reason := strings.TrimSpace(c.PostForm("reason"))
+	if !set {
+		reason = ""
+	}
+	switch typ {
+	case "yank":
+		f.Yanked, f.YankReason = set, reason
+	case "deprecate":
+		f.Deprecated, f.DeprecateReason = set, reason
+	}
+	key := r.key(id, scope, "flags", ver.String())
+	if f.Yanked || f.Deprecated {
+		b, err := json.Marshal(f)
+		if err != nil {
+			r.err(c, err)
+			return
+		}
+		if err := r.m.Set(string(b[:]), key); err != nil {
+			r.err(c, err)
+			return
+		}
+	} else if ok {
+		if err := r.m.Delete(key); err != nil {
+			r.err(c, err)
+			return
+		}
+	}
+	ev := &Event{Type: typ, Version: sc.format(ver), Reason: reason}
+	if !set {
+		ev.Type = "un" + typ
+	}
+	if err := r.record(id, scope, ev); err != nil {
+		r.err(c, err)
+		return
+	}
+	res := r.versioning(sc, ver)
+	f.apply(res)
+	r.echo(c, res)
+}
+
+// Yank marks an archived version as unusable
+func (r *Router) Yank(c *gin.Context) {
+	r.flag(c, "yank", true)
+}
+
+// Unyank clears the yank flag of an archived version
+func (r *Router) Unyank(c *gin.Context) {
+	r.flag(c, "yank", false)
+}
+
+// Deprecate marks an archived version as deprecated
+func (r *Router) Deprecate(c *gin.Context) {
+	r.flag(c, "deprecate", true)
+}
+
+// Undeprecate clears the deprecation flag of an archived version
+func (r *Router) Undeprecate(c *gin.Context) {
+	r.flag(c, "deprecate", false)
+}
+
+// Resolve returns the highest archived version matching a range, yanked
+// versions are never resolved
+func (r *Router) Resolve(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	scope, err := r.scope(c)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if _, err := r.current(id, scope); err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	match := func(v semver.Version) bool {
+		return len(v.Pre) == 0 || c.Query("prerelease") == "true"
+	}
+	if s := strings.TrimSpace(c.Query("range")); s != "" {
+		rng, err := semver.ParseRange(sc.rng(s))
+		if err != nil {
+			r.err(c, ErrInvalidRange)
+			return
+		}
+		match = rng
+	}
+	vers, err := r.archive(id, scope)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	all, err := r.flags(id, scope)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	var res *Versioning
+	var best semver.Version
+	for _, ver := range vers {
+		f, ok := all[ver.String()]
+		if ok && f.Yanked {
+			continue
+		}
+		if !match(ver) || (res != nil && ver.LTE(best)) {
+			continue
+		}
+		best = ver
+		res = r.versioning(sc, ver)
+		if ok {
+			f.apply(res)
+		}
+	}
+	if res == nil {
+		r.err(c, ErrVersionNotFound)
+		return
+	}
+	r.echo(c, res)
+}
+
+// latest returns the highest archived version of project `id` that is not yanked
+func (r *Router) latest(id string, scope []string) (semver.Version, error) {
+	var best semver.Version
+	vers, err := r.archive(id, scope)
+	if err != nil {
+		return best, err
+	}
+	all, err := r.flags(id, scope)
+	if err != nil {
+		return best, err
+	}
+	found := false
+	for _, ver := range vers {
+		if f, ok := all[ver.String()]; ok && f.Yanked {
+			continue
+		}
+		if !found || ver.GT(best) {
+			best, found = ver, true
+		}
+	}
+	if !found {
+		return best, ErrVersionNotFound
+	}
+	return best, nil
+}
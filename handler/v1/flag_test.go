@@ -0,0 +1,91 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+	s, done := serve(t)
+	defer done()
+	id := s.create("version=1.0.0")
+	p := "/v1/" + id
+	for _, ver := range []string{"1.1.0", "1.2.0", "2.0.0-rc.1"} {
+		s.must("POST", p, url.Values{"version": {ver}})
+	}
+
+	if got := s.must("POST", p+"/versions/1.2.0/yank", url.Values{"reason": {"broken build"}}); got != "1.2.0 (yanked: broken build)" {
+		t.Errorf("Yank = %q", got)
+	}
+	if got := s.must("POST", p+"/versions/1.2.0/deprecate", url.Values{"reason": {"use 1.1.0"}}); got != "1.2.0 (yanked: broken build, deprecated: use 1.1.0)" {
+		t.Errorf("Deprecate = %q", got)
+	}
+	if got := s.must("POST", p+"/versions/1.1.0/deprecate", url.Values{"reason": {"end of life"}}); got != "1.1.0 (deprecated: end of life)" {
+		t.Errorf("Deprecate = %q", got)
+	}
+	if code, body := s.do("POST", p+"/versions/3.0.0/yank", nil); code != http.StatusForbidden || body != ErrVersionNotFound.Error() {
+		t.Errorf("Yank of a missing version = %d %q", code, body)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "1.1.0 (deprecated: end of life)"},
+		{"?range=%3C1.9.0", "1.1.0 (deprecated: end of life)"},
+		{"?range=%3C1.1.0", "1.0.0"},
+		{"?prerelease=true", "2.0.0-rc.1"},
+	}
+	for _, tt := range tests {
+		if got := s.must("GET", p+"/resolve"+tt.query, nil); got != tt.want {
+			t.Errorf("Resolve%s = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+	if code, body := s.do("GET", p+"/resolve?range=%3E1.1.0%20%3C1.9.0", nil); code != http.StatusForbidden || body != ErrVersionNotFound.Error() {
+		t.Errorf("Resolve of yanked versions only = %d %q", code, body)
+	}
+	if code, body := s.do("GET", p+"/resolve?range=~%3E", nil); code != http.StatusForbidden || body != ErrInvalidRange.Error() {
+		t.Errorf("Resolve of an invalid range = %d %q", code, body)
+	}
+
+	var arch Archive
+	s.json("GET", p+"/history", nil, &arch)
+	for _, v := range arch.Versions {
+		if v.Version != "1.2.0" {
+			continue
+		}
+		if !v.Yanked || v.YankReason != "broken build" || !v.Deprecated || v.DeprecateReason != "use 1.1.0" {
+			t.Errorf("history of 1.2.0 = %+v", v)
+		}
+	}
+
+	// clearing a flag keeps the reason of the other one
+	if got := s.must("DELETE", p+"/versions/1.2.0/yank", nil); got != "1.2.0 (deprecated: use 1.1.0)" {
+		t.Errorf("Unyank = %q", got)
+	}
+	if got := s.must("DELETE", p+"/versions/1.2.0/deprecate", nil); got != "1.2.0" {
+		t.Errorf("Undeprecate = %q", got)
+	}
+	if got := s.must("GET", p+"/resolve", nil); got != "1.2.0" {
+		t.Errorf("Resolve after unyank = %q, want 1.2.0", got)
+	}
+}
+
+func TestLegacyFlags(t *testing.T) {
+	s, done := serve(t)
+	defer done()
+	id := s.create("version=1.0.0")
+	key := s.r.m.Path(id, "flags", "1.0.0")
+	if err := s.r.m.Set(`{"yanked":true,"deprecated":true,"reason":"shared"}`, key); err != nil {
+		t.Fatal(err)
+	}
+	all, err := s.r.flags(id, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := all["1.0.0"]
+	if f == nil || f.YankReason != "shared" || f.DeprecateReason != "shared" || f.Reason != "" {
+		t.Errorf("legacy flags = %+v", f)
+	}
+}
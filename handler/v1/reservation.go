@@ -0,0 +1,343 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/gin-gonic/gin"
+	"github.com/samuelngs/semver/backend"
+	"github.com/samuelngs/semver/pkg/env"
+	"github.com/satori/go.uuid"
+)
+
+// default and maximum ttl of reservations
+const (
+	defaultReservationTTL = time.Hour
+	maxReservationTTL     = 7 * 24 * time.Hour
+)
+
+// maxSkipped is the maximum number of reserved or released versions skipped
+// when looking for the next free version
+const maxSkipped = 100
+
+// lease is the stored value of a reservation
+type lease struct {
+	Owner string `json:"owner,omitempty"`
+	Token string `json:"token"`
+}
+
+// reservations returns the unexpired reservations of project `id` keyed by
+// version
+func (r *Router) reservations(id string, scope []string) (map[string]*backend.Lease, error) {
+	leases, err := r.m.Leases(r.key(id, scope, "reservations"))
+	if err == backend.ErrNotSupported {
+		return map[string]*backend.Lease{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	res := make(map[string]*backend.Lease, len(leases))
+	for _, l := range leases {
+		res[l.Key.Dirs[len(l.Key.Dirs)-1]] = l
+	}
+	return res, nil
+}
+
+// following returns the version following cur by type, reserved and
+// released versions are skipped so a bump never takes a version claimed by
+// a reservation or moves back to a version of the history
+func (r *Router) following(id string, sc *Scheme, scope []string, cur semver.Version, typ string) (semver.Version, error) {
+	reserved, err := r.reservations(id, scope)
+	if err != nil {
+		return cur, err
+	}
+	ver := cur
+	for i := 0; i < maxSkipped; i++ {
+		if ver, err = sc.next(ver, typ, time.Now().UTC()); err != nil {
+			return ver, err
+		}
+		if _, ok := reserved[ver.String()]; !ok && !r.archived(id, scope, ver) {
+			return ver, nil
+		}
+	}
+	return ver, ErrVersionReserved
+}
+
+// ttl returns the ttl of a reservation, SEMVER_RESERVATION_TTL when empty
+func (r *Router) ttl(s string) (time.Duration, error) {
+	if s == "" {
+		return env.Duration("SEMVER_RESERVATION_TTL", defaultReservationTTL), nil
+	}
+	ttl, err := time.ParseDuration(s)
+	if err != nil || ttl <= 0 || ttl > env.Duration("SEMVER_RESERVATION_MAX_TTL", maxReservationTTL) {
+		return 0, ErrInvalidTTL
+	}
+	return ttl, nil
+}
+
+// Reserve claims the next version of project `id` by type {major, minor,
+// patch} or an explicit `version` for a ttl, versions that are released or
+// reserved by someone else are skipped
+func (r *Router) Reserve(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	scope, err := r.scope(c)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	cur, err := r.current(id, scope)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	ttl, err := r.ttl(strings.TrimSpace(c.PostForm("ttl")))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	typ := c.PostForm("type")
+	if typ != "" && typ != "major" && typ != "minor" && typ != "patch" {
+		r.err(c, ErrInvalidBumpType)
+		return
+	}
+	l := &lease{Owner: strings.TrimSpace(c.PostForm("owner")), Token: uuid.NewV4().String()}
+	b, err := json.Marshal(l)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	explicit := strings.TrimSpace(c.PostForm("version"))
+	ver := cur
+	claimed := false
+	for i := 0; i < maxSkipped && !claimed; i++ {
+		if explicit != "" {
+			if ver, err = sc.parse(explicit); err != nil {
+				r.err(c, err)
+				return
+			}
+		} else if ver, err = sc.next(ver, typ, time.Now().UTC()); err != nil {
+			r.err(c, err)
+			return
+		}
+		if r.archived(id, scope, ver) {
+			if explicit != "" {
+				r.err(c, ErrVersionReleased)
+				return
+			}
+			continue
+		}
+		key := r.key(id, scope, "reservations", ver.String())
+		if claimed, err = r.m.Lease(key, string(b), ttl); err != nil {
+			r.err(c, err)
+			return
+		}
+		// a bump may have released the version meanwhile
+		if claimed && r.archived(id, scope, ver) {
+			if err := r.m.Revoke(key); err != nil {
+				r.err(c, err)
+				return
+			}
+			claimed = false
+		}
+		if !claimed && explicit != "" {
+			r.err(c, ErrVersionReserved)
+			return
+		}
+	}
+	if !claimed {
+		r.err(c, ErrVersionReserved)
+		return
+	}
+	if err := r.record(id, scope, &Event{Type: "reserve", Version: sc.format(ver), Reason: l.Owner}); err != nil {
+		r.err(c, err)
+		return
+	}
+	r.echo(c, &Reservation{
+		Version: sc.format(ver),
+		Owner:   l.Owner,
+		Token:   l.Token,
+		Expires: time.Now().Add(ttl).UTC().Format(time.RFC3339),
+	})
+}
+
+// Reservations lists the unexpired reservations of project `id`
+func (r *Router) Reservations(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	scope, err := r.scope(c)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if _, err := r.current(id, scope); err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	reserved, err := r.reservations(id, scope)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	vers := make([]semver.Version, 0, len(reserved))
+	for s := range reserved {
+		if ver, err := r.version(s); err == nil {
+			vers = append(vers, ver)
+		}
+	}
+	semver.Sort(vers)
+	list := &Reservations{Reservations: make([]*Reservation, 0, len(vers))}
+	for _, ver := range vers {
+		res := &Reservation{
+			Version: sc.format(ver),
+			Expires: reserved[ver.String()].Expires.UTC().Format(time.RFC3339),
+		}
+		var l lease
+		if json.Unmarshal([]byte(reserved[ver.String()].Val), &l) == nil {
+			res.Owner = l.Owner
+		}
+		list.Reservations = append(list.Reservations, res)
+	}
+	r.echo(c, list)
+}
+
+// reservation returns the unexpired reservation of a version after checking
+// the token of the request
+func (r *Router) reservation(c *gin.Context, id string, scope []string, ver semver.Version) (*backend.Key, *lease, error) {
+	reserved, err := r.reservations(id, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+	found, ok := reserved[ver.String()]
+	if !ok {
+		return nil, nil, ErrReservationNotFound
+	}
+	var l lease
+	if err := json.Unmarshal([]byte(found.Val), &l); err != nil {
+		return nil, nil, err
+	}
+	if token := c.DefaultPostForm("token", c.Query("token")); token != l.Token {
+		return nil, nil, ErrInvalidReservationToken
+	}
+	return found.Key, &l, nil
+}
+
+// CommitReservation releases a reserved version of project `id`, the token
+// returned by the reservation is required. The version is added to the
+// history and only becomes current when it is above the current version.
+func (r *Router) CommitReservation(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	scope, err := r.scope(c)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	cur, err := r.current(id, scope)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	ver, err := sc.parse(c.Param("version"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	key, l, err := r.reservation(c, id, scope, ver)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	// the version is always archived, the current version only moves forward
+	// so a late commit of a lower reservation does not roll the project back
+	keys := []*backend.Key{r.key(id, scope, "archive", ver.String())}
+	if ver.GT(cur) {
+		keys = append(keys, r.key(id, scope, "version"))
+	}
+	if err := r.m.Set(ver.String(), keys...); err != nil {
+		r.err(c, err)
+		return
+	}
+	if err := r.m.Revoke(key); err != nil {
+		r.err(c, err)
+		return
+	}
+	if err := r.record(id, scope, &Event{Type: "commit", Version: sc.format(ver), Reason: l.Owner}); err != nil {
+		r.err(c, err)
+		return
+	}
+	r.echo(c, r.versioning(sc, ver))
+}
+
+// DeleteReservation gives up a reserved version of project `id` before it
+// expires, the token returned by the reservation is required
+func (r *Router) DeleteReservation(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	scope, err := r.scope(c)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if _, err := r.current(id, scope); err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	ver, err := sc.parse(c.Param("version"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	key, l, err := r.reservation(c, id, scope, ver)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if err := r.m.Revoke(key); err != nil {
+		r.err(c, err)
+		return
+	}
+	if err := r.record(id, scope, &Event{Type: "unreserve", Version: sc.format(ver), Reason: l.Owner}); err != nil {
+		r.err(c, err)
+		return
+	}
+	c.String(http.StatusOK, "ok")
+}
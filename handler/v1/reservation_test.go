@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestReservations(t *testing.T) {
+	s, done := serve(t)
+	defer done()
+	id := s.create("version=1.4.0")
+	p := "/v1/" + id
+	reserve := func(form url.Values) *Reservation {
+		t.Helper()
+		res := new(Reservation)
+		if code := s.json("POST", p+"/reservations", form, res); code != http.StatusOK {
+			t.Fatalf("Reserve(%v) = %d", form, code)
+		}
+		return res
+	}
+
+	a := reserve(url.Values{"type": {"minor"}, "owner": {"release-a"}})
+	b := reserve(url.Values{"type": {"minor"}, "owner": {"release-b"}})
+	if a.Version != "1.5.0" || b.Version != "1.6.0" || a.Token == "" || a.Token == b.Token {
+		t.Fatalf("reservations = %+v, %+v", a, b)
+	}
+	// bumps skip reserved versions
+	if got := s.must("GET", p+"/bump?type=minor", nil); got != "1.7.0" {
+		t.Errorf("Bump = %q, want 1.7.0", got)
+	}
+	var list Reservations
+	s.json("GET", p+"/reservations", nil, &list)
+	if len(list.Reservations) != 2 || list.Reservations[0].Owner != "release-a" || list.Reservations[1].Version != "1.6.0" || list.Reservations[0].Token != "" {
+		t.Errorf("Reservations = %+v", list.Reservations)
+	}
+
+	errs := []struct {
+		method string
+		path   string
+		form   url.Values
+		want   error
+	}{
+		{"POST", p + "/reservations", url.Values{"version": {"1.5.0"}}, ErrVersionReserved},
+		{"POST", p + "/reservations", url.Values{"version": {"1.7.0"}}, ErrVersionReleased},
+		{"POST", p + "/reservations", url.Values{"ttl": {"0s"}}, ErrInvalidTTL},
+		{"POST", p + "/reservations", url.Values{"ttl": {"1000h"}}, ErrInvalidTTL},
+		{"POST", p + "/reservations", url.Values{"type": {"huge"}}, ErrInvalidBumpType},
+		{"POST", p + "/reservations/1.6.0/commit", url.Values{"token": {a.Token}}, ErrInvalidReservationToken},
+		{"POST", p + "/reservations/1.9.0/commit", url.Values{"token": {a.Token}}, ErrReservationNotFound},
+		{"DELETE", p + "/reservations/1.5.0?token=" + b.Token, nil, ErrInvalidReservationToken},
+	}
+	for _, tt := range errs {
+		if code, body := s.do(tt.method, tt.path, tt.form); code != http.StatusForbidden || body != tt.want.Error() {
+			t.Errorf("%s %s = %d %q, want %v", tt.method, tt.path, code, body, tt.want)
+		}
+	}
+
+	// a commit below the current version is released without moving it
+	if got := s.must("POST", p+"/reservations/1.6.0/commit", url.Values{"token": {b.Token}}); got != "1.6.0" {
+		t.Errorf("CommitReservation = %q", got)
+	}
+	if got := s.must("GET", p, nil); got != "1.7.0" {
+		t.Errorf("version after a lower commit = %q, want 1.7.0", got)
+	}
+	if got, want := s.must("GET", p+"/history", nil), "1.4.0\n1.6.0\n1.7.0"; got != want {
+		t.Errorf("History = %q, want %q", got, want)
+	}
+	c := reserve(url.Values{"type": {"minor"}})
+	if got := s.must("POST", p+"/reservations/"+c.Version+"/commit", url.Values{"token": {c.Token}}); got != "1.8.0" {
+		t.Errorf("CommitReservation = %q, want 1.8.0", got)
+	}
+	if got := s.must("GET", p, nil); got != "1.8.0" {
+		t.Errorf("version after a commit = %q, want 1.8.0", got)
+	}
+
+	if got := s.must("DELETE", p+"/reservations/1.5.0?token="+a.Token, nil); got != "ok" {
+		t.Errorf("DeleteReservation = %q", got)
+	}
+	if got := s.must("GET", p+"/reservations", nil); got != "" {
+		t.Errorf("Reservations after commit and delete = %q", got)
+	}
+
+	// expired reservations are skipped and can be claimed again
+	d := reserve(url.Values{"type": {"patch"}, "ttl": {"1s"}})
+	time.Sleep(1100 * time.Millisecond)
+	if got := s.must("GET", p+"/reservations", nil); got != "" {
+		t.Errorf("Reservations after expiry = %q", got)
+	}
+	if e := reserve(url.Values{"version": {d.Version}}); e.Version != "1.8.1" {
+		t.Errorf("Reserve of an expired version = %+v", e)
+	}
+
+	// a purge releases the reservations of the project
+	reserve(url.Values{"type": {"minor"}})
+	s.must("POST", p+"/components/api", url.Values{"version": {"1.0.0"}})
+	s.must("POST", p+"/components/api/reservations", url.Values{"type": {"major"}})
+	if err := s.r.purge(id); err != nil {
+		t.Fatal(err)
+	}
+	if leases, err := s.r.m.Leases(s.r.m.Path(id)); err != nil || len(leases) != 0 {
+		t.Errorf("leases after purge = %v, %v", leases, err)
+	}
+}
@@ -1,18 +1,52 @@
 package v1
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"runtime/debug"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/blang/semver"
 	"github.com/gin-gonic/gin"
 	"github.com/samuelngs/semver/backend"
+	"github.com/samuelngs/semver/pkg/admin"
+	"github.com/samuelngs/semver/pkg/api"
+	"github.com/samuelngs/semver/pkg/logger"
 	"github.com/satori/go.uuid"
 )
 
+// maxFormMemory is the memory used by multipart forms before files are
+// written to disk
+const maxFormMemory = 32 << 20
+
+// Authorizer checks if a request has the access {read, write} to a project
+type Authorizer func(c *gin.Context, project, access string) bool
+
 // Router route
 type Router struct {
 	m *backend.Manager
+
+	// authorize checks the projects of a batch, nil allows every project
+	authorize Authorizer
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Close stops background workers and waits for them to finish
+func (r *Router) Close() {
+	close(r.quit)
+	r.wg.Wait()
+}
+
+// Authorize sets the project access check of batches, requests to a single
+// project are checked by the middleware of the server
+func (r *Router) Authorize(fn Authorizer) {
+	r.authorize = fn
 }
 
 // Uniq generate unique id
@@ -51,31 +85,72 @@ func (r *Router) uuid(s string) (uuid.UUID, error) {
 
 func (r *Router) release(c *gin.Context) {
 	if o := recover(); o != nil {
+		logger.Error("panic recovered", logger.Fields{
+			"request_id": logger.RequestID(c),
+			"project":    c.Param("id"),
+			"panic":      fmt.Sprint(o),
+			"stack":      string(debug.Stack()),
+		})
 		r.err(c, ErrInternalServer)
 	}
 }
 
 // Err prints error message
 func (r *Router) err(c *gin.Context, e error) {
+	code := status(e)
+	w := &Warning{Error: e.Error(), RequestID: logger.RequestID(c)}
 	switch c.DefaultQuery("output", "text") {
 	case "xml":
-		c.XML(http.StatusForbidden, &Warning{e.Error()})
+		c.XML(code, w)
 	case "json":
-		c.JSON(http.StatusForbidden, &Warning{e.Error()})
+		c.JSON(code, w)
 	default:
-		c.String(http.StatusForbidden, "%v", e)
+		c.String(code, "%v", e)
+	}
+}
+
+// status returns the status code of an error, errors of the api are answered
+// with 403 and any other error, e.g. a failing storage backend, is a server
+// error
+func status(e error) int {
+	switch {
+	case e == ErrInternalServer:
+		return http.StatusInternalServerError
+	case e == backend.ErrConflict:
+		return http.StatusConflict
+	case e == backend.ErrNotSupported:
+		return http.StatusNotImplemented
+	case e == backend.ErrRecordNotFound, api.Known(e.Error()) == e:
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// form parses the urlencoded or multipart form values of the request body
+func (r *Router) form(c *gin.Context) (url.Values, error) {
+	if err := c.Request.ParseForm(); err != nil {
+		return nil, ErrInvalidRequestBody
 	}
+	if err := c.Request.ParseMultipartForm(maxFormMemory); err != nil && err != http.ErrNotMultipart {
+		return nil, ErrInvalidRequestBody
+	}
+	return c.Request.PostForm, nil
 }
 
 // Echo prints data message
 func (r *Router) echo(c *gin.Context, d interface{}) {
+	r.respond(c, http.StatusOK, d)
+}
+
+// respond prints data message with status code
+func (r *Router) respond(c *gin.Context, code int, d interface{}) {
 	switch c.DefaultQuery("output", "text") {
 	case "xml":
-		c.XML(http.StatusOK, d)
+		c.XML(code, d)
 	case "json":
-		c.JSON(http.StatusOK, d)
+		c.JSON(code, d)
 	default:
-		c.String(http.StatusOK, "%v", d)
+		c.String(code, "%v", d)
 	}
 }
 
@@ -88,22 +163,29 @@ func (r *Router) Default(c *gin.Context) {
 // Create is the new semver handler
 func (r *Router) Create(c *gin.Context) {
 	defer r.release(c)
-	var s string
-	if v := strings.TrimSpace(c.Query("version")); v != "" {
-		s = v
-	} else {
-		s = defaultVersion
-	}
-	ver, err := r.version(s)
+	sc, err := newScheme(c.Query("scheme"), c.Query("format"))
 	if err != nil {
 		r.err(c, err)
 		return
 	}
+	ver := sc.initial(time.Now().UTC())
+	if v := strings.TrimSpace(c.Query("version")); v != "" {
+		if ver, err = sc.parse(v); err != nil {
+			r.err(c, err)
+			return
+		}
+	}
 	id, err := r.uniq()
 	if err != nil {
 		r.err(c, err)
 		return
 	}
+	if sc.Name != SchemeSemver {
+		if err := r.m.Set(sc.record(), r.m.Path(id, "scheme")); err != nil {
+			r.err(c, err)
+			return
+		}
+	}
 	if err := r.m.Set(
 		ver.String(),
 		r.m.Path(id, "version"),
@@ -112,14 +194,8 @@ func (r *Router) Create(c *gin.Context) {
 		r.err(c, err)
 		return
 	}
-	res := &Versioning{
-		Project: id,
-		Version: ver.String(),
-		Major:   ver.Major,
-		Minor:   ver.Minor,
-		Patch:   ver.Patch,
-		Build:   ver.Build,
-	}
+	res := r.versioning(sc, ver)
+	res.Project = id
 	r.echo(c, res)
 }
 
@@ -135,25 +211,36 @@ func (r *Router) Get(c *gin.Context) {
 		r.err(c, err)
 		return
 	}
-	vers, err := r.m.Get(
-		r.m.Path(id, "version"),
-	)
-	if err != nil || len(vers) <= 0 {
-		r.err(c, ErrProjectNotFound)
+	scope, err := r.scope(c)
+	if err != nil {
+		r.err(c, err)
 		return
 	}
-	ver, err := r.version(vers[0])
+	ver, err := r.current(id, scope)
 	if err != nil {
 		r.err(c, err)
 		return
 	}
-	res := &Versioning{
-		Version: ver.String(),
-		Major:   ver.Major,
-		Minor:   ver.Minor,
-		Patch:   ver.Patch,
-		Build:   ver.Build,
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
 	}
+	if s := c.Query("tag"); s != "" {
+		name, err := r.tag(s)
+		if err != nil {
+			r.err(c, err)
+			return
+		}
+		if ver, err = r.tagged(id, scope, name); err == ErrTagNotFound && name == "latest" {
+			ver, err = r.latest(id, scope)
+		}
+		if err != nil {
+			r.err(c, err)
+			return
+		}
+	}
+	res := r.versioning(sc, ver)
 	r.echo(c, res)
 }
 
@@ -161,11 +248,15 @@ func (r *Router) Get(c *gin.Context) {
 func (r *Router) Set(c *gin.Context) {
 	defer r.release(c)
 	id := c.Param("id")
+	if id == "batch" && c.Param("name") == "" {
+		r.Batch(c)
+		return
+	}
 	if _, err := r.uuid(id); err != nil {
 		r.err(c, err)
 		return
 	}
-	exists, err := r.m.Exists(r.m.Path(id, "version"))
+	exists, err := r.exists(id)
 	if err != nil {
 		r.err(c, err)
 		return
@@ -173,26 +264,30 @@ func (r *Router) Set(c *gin.Context) {
 		r.err(c, ErrProjectNotFound)
 		return
 	}
-	ver, err := r.version(c.PostForm("version"))
+	scope, err := r.scope(c)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	ver, err := sc.parse(strings.TrimSpace(c.PostForm("version")))
 	if err != nil {
 		r.err(c, err)
 		return
 	}
 	if err := r.m.Set(
 		ver.String(),
-		r.m.Path(id, "version"),
-		r.m.Path(id, "archive", ver.String()),
+		r.key(id, scope, "version"),
+		r.key(id, scope, "archive", ver.String()),
 	); err != nil {
 		r.err(c, err)
 		return
 	}
-	res := &Versioning{
-		Version: ver.String(),
-		Major:   ver.Major,
-		Minor:   ver.Minor,
-		Patch:   ver.Patch,
-		Build:   ver.Build,
-	}
+	res := r.versioning(sc, ver)
 	r.echo(c, res)
 }
 
@@ -204,27 +299,51 @@ func (r *Router) Bump(c *gin.Context) {
 		r.err(c, err)
 		return
 	}
-	exists, err := r.m.Exists(r.m.Path(id, "version"))
+	scope, err := r.scope(c)
 	if err != nil {
 		r.err(c, err)
 		return
-	} else if !exists {
-		r.err(c, ErrProjectNotFound)
+	}
+	cur, err := r.current(id, scope)
+	if err != nil {
+		r.err(c, err)
 		return
 	}
-	vers, err := r.m.Get(
-		r.m.Path(id, "version"),
-	)
-	if len(vers) <= 0 {
-		r.err(c, ErrProjectNotFound)
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
 		return
 	}
-	ver, err := r.version(vers[0])
+	ver, err := r.bumpFrom(id, sc, scope, cur, c.Query("type"))
 	if err != nil {
 		r.err(c, err)
 		return
 	}
-	if typ := c.Query("type"); typ == "major" {
+	res := r.versioning(sc, ver)
+	r.echo(c, res)
+}
+
+// bumpFrom increases the version cur of project `id` by type {major, minor,
+// patch} following the versioning scheme, reserved versions are skipped. cur
+// is the version the bump type was derived from.
+func (r *Router) bumpFrom(id string, sc *Scheme, scope []string, cur semver.Version, typ string) (semver.Version, error) {
+	ver, err := r.following(id, sc, scope, cur, typ)
+	if err != nil {
+		return ver, err
+	}
+	if err := r.m.Set(
+		ver.String(),
+		r.key(id, scope, "version"),
+		r.key(id, scope, "archive", ver.String()),
+	); err != nil {
+		return ver, err
+	}
+	return ver, nil
+}
+
+// next returns the version following ver by type {major, minor, patch}
+func next(ver semver.Version, typ string) semver.Version {
+	if typ == "major" {
 		ver.Major++
 		ver.Minor = 0
 		ver.Patch = 0
@@ -235,24 +354,67 @@ func (r *Router) Bump(c *gin.Context) {
 		ver.Patch++
 	}
 	ver.Pre = make([]semver.PRVersion, 0)
-	if err := ver.Validate(); err != nil {
+	return ver
+}
+
+// AutoBump bumps version by conventional commit messages
+func (r *Router) AutoBump(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
 		r.err(c, err)
 		return
 	}
-	if err := r.m.Set(
-		ver.String(),
-		r.m.Path(id, "version"),
-		r.m.Path(id, "archive", ver.String()),
-	); err != nil {
+	var msgs []string
+	if strings.HasPrefix(c.ContentType(), "application/json") {
+		var body struct {
+			Commits []string `json:"commits"`
+		}
+		if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil {
+			r.err(c, ErrInvalidRequestBody)
+			return
+		}
+		msgs = body.Commits
+	} else {
+		form, err := r.form(c)
+		if err != nil {
+			r.err(c, err)
+			return
+		}
+		msgs = form["commits"]
+	}
+	scope, err := r.scope(c)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	cur, err := r.current(id, scope)
+	if err != nil {
 		r.err(c, err)
 		return
 	}
-	res := &Versioning{
-		Version: ver.String(),
-		Major:   ver.Major,
-		Minor:   ver.Minor,
-		Patch:   ver.Patch,
-		Build:   ver.Build,
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	// only semver treats a 0 major as initial development, calver and build
+	// schemes bump by their own rules whatever the type
+	typ, changelog := conventional(sc.Name == SchemeSemver && cur.Major == 0, msgs)
+	if typ == "" {
+		r.err(c, ErrNoReleasableChanges)
+		return
+	}
+	// the bump type is derived from cur, bump from the same version
+	ver, err := r.bumpFrom(id, sc, scope, cur, typ)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	res := &Release{
+		Type:      typ,
+		Version:   r.versioning(sc, ver),
+		Changelog: changelog,
 	}
 	r.echo(c, res)
 }
@@ -265,7 +427,7 @@ func (r *Router) History(c *gin.Context) {
 		r.err(c, err)
 		return
 	}
-	exists, err := r.m.Exists(r.m.Path(id, "version"))
+	exists, err := r.exists(id)
 	if err != nil {
 		r.err(c, err)
 		return
@@ -273,12 +435,28 @@ func (r *Router) History(c *gin.Context) {
 		r.err(c, ErrProjectNotFound)
 		return
 	}
-	keys, err := r.m.List(r.m.Path(id, "archive"))
+	scope, err := r.scope(c)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if len(scope) > 0 {
+		if _, err := r.current(id, scope); err != nil {
+			r.err(c, err)
+			return
+		}
+	}
+	vers, err := r.archive(id, scope)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	all, err := r.flags(id, scope)
 	if err != nil {
 		r.err(c, err)
 		return
 	}
-	vers, err := r.m.Get(keys...)
+	sc, err := r.scheme(id)
 	if err != nil {
 		r.err(c, err)
 		return
@@ -286,24 +464,36 @@ func (r *Router) History(c *gin.Context) {
 	arch := &Archive{
 		Versions: make([]*Versioning, len(vers)),
 	}
-	for i, s := range vers {
-		ver, err := r.version(s)
-		if err != nil {
-			r.err(c, err)
-			return
+	for i, ver := range vers {
+		arch.Versions[i] = r.versioning(sc, ver)
+		if f, ok := all[ver.String()]; ok {
+			f.apply(arch.Versions[i])
+		}
+	}
+	if arch.Events, err = r.events(id, scope); err != nil {
+		r.err(c, err)
+		return
+	}
+	issued := make(map[string]map[string]int64)
+	for _, ev := range arch.Events {
+		if ev.Type != "counter" {
+			continue
+		}
+		if issued[ev.Version] == nil {
+			issued[ev.Version] = make(map[string]int64)
 		}
-		arch.Versions[i] = &Versioning{
-			Version: ver.String(),
-			Major:   ver.Major,
-			Minor:   ver.Minor,
-			Patch:   ver.Patch,
-			Build:   ver.Build,
+		if ev.Value > issued[ev.Version][ev.Counter] {
+			issued[ev.Version][ev.Counter] = ev.Value
 		}
 	}
+	for _, v := range arch.Versions {
+		v.Counters = issued[v.Version]
+	}
 	r.echo(c, arch)
 }
 
-// Delete to remove project
+// Delete to remove project, the project is kept in the trash for the
+// retention period unless `purge` is requested by an administrator
 func (r *Router) Delete(c *gin.Context) {
 	defer r.release(c)
 	id := c.Param("id")
@@ -311,7 +501,27 @@ func (r *Router) Delete(c *gin.Context) {
 		r.err(c, err)
 		return
 	}
-	exists, err := r.m.Exists(r.m.Path(id, "version"))
+	if c.Query("purge") == "true" {
+		if !admin.Authorized(c.Request) {
+			r.err(c, ErrForbidden)
+			return
+		}
+		exists, err := r.m.Exists(r.m.Path(id, "version"))
+		if err != nil {
+			r.err(c, err)
+			return
+		} else if !exists {
+			r.err(c, ErrProjectNotFound)
+			return
+		}
+		if err := r.purge(id); err != nil {
+			r.err(c, err)
+			return
+		}
+		c.String(http.StatusOK, "ok")
+		return
+	}
+	exists, err := r.exists(id)
 	if err != nil {
 		r.err(c, err)
 		return
@@ -319,12 +529,12 @@ func (r *Router) Delete(c *gin.Context) {
 		r.err(c, ErrProjectNotFound)
 		return
 	}
-	keys, err := r.m.List(r.m.Path(id))
-	if err != nil {
-		r.err(c, err)
-		return
-	}
-	if err := r.m.Delete(keys...); err != nil {
+	now := time.Now().UTC().Format(time.RFC3339)
+	if err := r.m.Set(
+		now,
+		r.m.Path(id, "deleted"),
+		r.m.Path(trash, id),
+	); err != nil {
 		r.err(c, err)
 		return
 	}
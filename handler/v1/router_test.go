@@ -0,0 +1,31 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/samuelngs/semver/backend"
+)
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{ErrProjectNotFound, http.StatusForbidden},
+		{ErrInvalidRequestBody, http.StatusForbidden},
+		{ErrForbidden, http.StatusForbidden},
+		{backend.ErrRecordNotFound, http.StatusForbidden},
+		{backend.ErrConflict, http.StatusConflict},
+		{backend.ErrNotSupported, http.StatusNotImplemented},
+		{ErrInternalServer, http.StatusInternalServerError},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+		{errors.New(ErrProjectNotFound.Error()), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := status(tt.err); got != tt.want {
+			t.Errorf("status(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package v1
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/gin-gonic/gin"
+	"github.com/samuelngs/semver/backend"
+	"github.com/samuelngs/semver/pkg/api"
+)
+
+// Versioning schemes
+const (
+	SchemeSemver = api.SchemeSemver
+	SchemeCalver = api.SchemeCalver
+	SchemeBuild  = api.SchemeBuild
+)
+
+// defaultCalverFormat is the calver format when none is given
+const defaultCalverFormat = "YYYY.MM.MICRO"
+
+// calendar tokens of a calver format, the padded tokens are zero-padded to
+// two digits, YY is the year since 2000
+var calverTokens = map[string]bool{
+	"YYYY": true, "YY": true, "0Y": true,
+	"MM": true, "0M": true,
+	"WW": true, "0W": true,
+	"DD": true, "0D": true,
+	"MICRO": true,
+}
+
+// version operands of a range, e.g. 2026.01.3 in >=2026.01.3
+var rangeOperand = regexp.MustCompile(`[0-9]+(?:\.[0-9]+)*`)
+
+// Scheme is the versioning scheme of a project. Versions of every scheme are
+// kept as semantic versions so the archive sorts and ranges match them, e.g.
+// calver 2026.01.3 is 2026.1.3 and build number 42 is 42.0.0.
+type Scheme struct {
+	Name   string
+	Format string
+
+	tokens []string
+}
+
+// newScheme validates a scheme name and its calver format
+func newScheme(name, format string) (*Scheme, error) {
+	switch name {
+	case "", SchemeSemver:
+		return &Scheme{Name: SchemeSemver}, nil
+	case SchemeBuild:
+		return &Scheme{Name: SchemeBuild}, nil
+	case SchemeCalver:
+	default:
+		return nil, ErrInvalidScheme
+	}
+	if format == "" {
+		format = defaultCalverFormat
+	}
+	tokens := strings.Split(format, ".")
+	if len(tokens) < 2 || len(tokens) > 3 {
+		return nil, ErrInvalidCalverFormat
+	}
+	for i, t := range tokens {
+		if !calverTokens[t] || (t == "MICRO" && i != len(tokens)-1) {
+			return nil, ErrInvalidCalverFormat
+		}
+	}
+	if t := tokens[0]; t != "YYYY" && t != "YY" && t != "0Y" {
+		return nil, ErrInvalidCalverFormat
+	}
+	return &Scheme{Name: SchemeCalver, Format: format, tokens: tokens}, nil
+}
+
+// record returns the stored value of the scheme
+func (s *Scheme) record() string {
+	if s.Format != "" {
+		return s.Name + ":" + s.Format
+	}
+	return s.Name
+}
+
+// scheme returns the versioning scheme of project `id`, semver when unset
+func (r *Router) scheme(id string) (*Scheme, error) {
+	vals, err := r.m.Get(r.m.Path(id, "scheme"))
+	if err == backend.ErrRecordNotFound || (err == nil && (len(vals) == 0 || vals[0] == "")) {
+		return newScheme(SchemeSemver, "")
+	} else if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(vals[0], ":", 2)
+	if len(parts) == 1 {
+		parts = append(parts, "")
+	}
+	return newScheme(parts[0], parts[1])
+}
+
+// initial returns the version of a new project
+func (s *Scheme) initial(now time.Time) semver.Version {
+	switch s.Name {
+	case SchemeCalver:
+		var ver semver.Version
+		s.set(&ver, s.date(now))
+		return ver
+	case SchemeBuild:
+		return semver.Version{Major: 1}
+	}
+	return semver.MustParse(defaultVersion)
+}
+
+// parse parses a version of the scheme
+func (s *Scheme) parse(str string) (semver.Version, error) {
+	var ver semver.Version
+	switch s.Name {
+	case SchemeCalver:
+		parts := strings.Split(str, ".")
+		if len(parts) != len(s.tokens) {
+			return ver, ErrInvalidVersioningFormat
+		}
+		vals := make([]uint64, len(parts))
+		for i, p := range parts {
+			padded := strings.HasPrefix(s.tokens[i], "0")
+			if padded && len(p) != 2 {
+				return ver, ErrInvalidVersioningFormat
+			}
+			n, err := number(p, padded)
+			if err != nil {
+				return ver, err
+			}
+			vals[i] = n
+		}
+		s.set(&ver, vals)
+		return ver, nil
+	case SchemeBuild:
+		n, err := number(str, false)
+		if err != nil {
+			return ver, err
+		}
+		return semver.Version{Major: n}, nil
+	}
+	v, err := semver.Make(str)
+	if err != nil {
+		return v, ErrInvalidVersioningFormat
+	}
+	return v, nil
+}
+
+// number parses a version number, leading zeros are only valid when padded
+func number(s string, padded bool) (uint64, error) {
+	if s == "" || (!padded && len(s) > 1 && s[0] == '0') {
+		return 0, ErrInvalidVersioningFormat
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidVersioningFormat
+	}
+	return n, nil
+}
+
+// format returns the version in the notation of the scheme
+func (s *Scheme) format(ver semver.Version) string {
+	switch s.Name {
+	case SchemeCalver:
+		vals := []uint64{ver.Major, ver.Minor, ver.Patch}
+		parts := make([]string, len(s.tokens))
+		for i, t := range s.tokens {
+			if strings.HasPrefix(t, "0") {
+				parts[i] = fmt.Sprintf("%02d", vals[i])
+			} else {
+				parts[i] = strconv.FormatUint(vals[i], 10)
+			}
+		}
+		return strings.Join(parts, ".")
+	case SchemeBuild:
+		return strconv.FormatUint(ver.Major, 10)
+	}
+	return ver.String()
+}
+
+// next returns the version following ver, the bump type only applies to
+// semver. A calver version moves to the current period and resets the micro
+// part, or increases the micro part within the same period.
+func (s *Scheme) next(ver semver.Version, typ string, now time.Time) (semver.Version, error) {
+	switch s.Name {
+	case SchemeCalver:
+		cur := []uint64{ver.Major, ver.Minor, ver.Patch}[:len(s.tokens)]
+		date := s.date(now)
+		for i, t := range s.tokens {
+			if t == "MICRO" {
+				break
+			}
+			if date[i] > cur[i] {
+				s.set(&ver, date)
+				return ver, nil
+			} else if date[i] < cur[i] {
+				// the current version is ahead of the clock, keep its period
+				break
+			}
+		}
+		last := len(s.tokens) - 1
+		if s.tokens[last] != "MICRO" {
+			return ver, ErrCalendarPeriodReleased
+		}
+		cur[last]++
+		s.set(&ver, cur)
+		return ver, nil
+	case SchemeBuild:
+		return semver.Version{Major: ver.Major + 1}, nil
+	}
+	ver = next(ver, typ)
+	return ver, ver.Validate()
+}
+
+// date returns the calendar values of the format at time now, micro is 0
+func (s *Scheme) date(now time.Time) []uint64 {
+	vals := make([]uint64, len(s.tokens))
+	for i, t := range s.tokens {
+		switch t {
+		case "YYYY":
+			vals[i] = uint64(now.Year())
+		case "YY", "0Y":
+			vals[i] = uint64(now.Year() - 2000)
+		case "MM", "0M":
+			vals[i] = uint64(now.Month())
+		case "WW", "0W":
+			_, week := now.ISOWeek()
+			vals[i] = uint64(week)
+		case "DD", "0D":
+			vals[i] = uint64(now.Day())
+		}
+	}
+	return vals
+}
+
+// set assigns calver values to the version fields
+func (s *Scheme) set(ver *semver.Version, vals []uint64) {
+	fields := []*uint64{&ver.Major, &ver.Minor, &ver.Patch}
+	for i := range fields {
+		*fields[i] = 0
+		if i < len(vals) {
+			*fields[i] = vals[i]
+		}
+	}
+	ver.Pre = nil
+	ver.Build = nil
+}
+
+// rng rewrites the version operands of a range to semantic versions
+func (s *Scheme) rng(str string) string {
+	if s.Name == SchemeSemver {
+		return str
+	}
+	return rangeOperand.ReplaceAllStringFunc(str, func(op string) string {
+		ver, err := s.parse(op)
+		if err != nil {
+			return op
+		}
+		return ver.String()
+	})
+}
+
+// versioning creates the response of version ver
+func (r *Router) versioning(sc *Scheme, ver semver.Version) *Versioning {
+	return &Versioning{
+		Version: sc.format(ver),
+		Major:   ver.Major,
+		Minor:   ver.Minor,
+		Patch:   ver.Patch,
+		Build:   ver.Build,
+	}
+}
+
+// GetScheme returns the versioning scheme of project `id`
+func (r *Router) GetScheme(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	exists, err := r.exists(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	} else if !exists {
+		r.err(c, ErrProjectNotFound)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	r.echo(c, &api.Scheme{Name: sc.Name, Format: sc.Format})
+}
@@ -0,0 +1,178 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blang/semver"
+)
+
+func TestNewScheme(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   string
+		err    error
+	}{
+		{"", "", "semver", nil},
+		{"semver", "YYYY.MM", "semver", nil},
+		{"build", "", "build", nil},
+		{"calver", "", "calver:YYYY.MM.MICRO", nil},
+		{"calver", "YY.0M.0D", "calver:YY.0M.0D", nil},
+		{"calver", "0Y.0W.MICRO", "calver:0Y.0W.MICRO", nil},
+		{"calver", "YYYY.MM", "calver:YYYY.MM", nil},
+		{"romver", "", "", ErrInvalidScheme},
+		{"calver", "YYYY", "", ErrInvalidCalverFormat},
+		{"calver", "YYYY.MM.DD.MICRO", "", ErrInvalidCalverFormat},
+		{"calver", "MM.YYYY", "", ErrInvalidCalverFormat},
+		{"calver", "YYYY.MICRO.MM", "", ErrInvalidCalverFormat},
+		{"calver", "YYYY.mm", "", ErrInvalidCalverFormat},
+	}
+	for _, tt := range tests {
+		sc, err := newScheme(tt.name, tt.format)
+		if err != tt.err {
+			t.Errorf("newScheme(%q, %q) error = %v, want %v", tt.name, tt.format, err, tt.err)
+			continue
+		}
+		if err == nil && sc.record() != tt.want {
+			t.Errorf("newScheme(%q, %q) = %q, want %q", tt.name, tt.format, sc.record(), tt.want)
+		}
+	}
+}
+
+func TestSchemeParse(t *testing.T) {
+	tests := []struct {
+		format string
+		str    string
+		want   string
+		err    bool
+	}{
+		{"semver", "1.2.3-rc.1+b5", "1.2.3-rc.1+b5", false},
+		{"semver", "1.2", "", true},
+		{"build", "42", "42.0.0", false},
+		{"build", "042", "", true},
+		{"build", "1.0.0", "", true},
+		{"YYYY.MM.MICRO", "2026.1.3", "2026.1.3", false},
+		{"YYYY.MM.MICRO", "2026.01.3", "", true},
+		{"YYYY.0M.MICRO", "2026.01.3", "2026.1.3", false},
+		{"YYYY.0M.MICRO", "2026.1.3", "", true},
+		{"YYYY.0M.MICRO", "2026.001.3", "", true},
+		{"YY.0M.0D", "26.03.09", "26.3.9", false},
+		{"0Y.0W", "06.52", "6.52.0", false},
+		{"YYYY.MM", "2026.1.0", "", true},
+		{"YYYY.MM.MICRO", "2026.x.1", "", true},
+	}
+	for _, tt := range tests {
+		sc := scheme(t, tt.format)
+		ver, err := sc.parse(tt.str)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: parse(%q) = %s, expected an error", tt.format, tt.str, ver)
+			}
+			continue
+		}
+		if err != nil || ver.String() != tt.want {
+			t.Errorf("%s: parse(%q) = %s, %v, want %s", tt.format, tt.str, ver, err, tt.want)
+			continue
+		}
+		// the notation of the scheme survives a round trip
+		if got := sc.format(ver); got != tt.str && tt.format != "semver" {
+			t.Errorf("%s: format(%s) = %q, want %q", tt.format, ver, got, tt.str)
+		}
+	}
+}
+
+func TestSchemeNext(t *testing.T) {
+	now := time.Date(2026, time.March, 9, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		format string
+		cur    string
+		typ    string
+		want   string
+		err    error
+	}{
+		{"semver", "1.2.3", "major", "2.0.0", nil},
+		{"semver", "1.2.3", "minor", "1.3.0", nil},
+		{"semver", "1.2.3-rc.1", "patch", "1.2.4", nil},
+		{"build", "41", "major", "42", nil},
+		{"YYYY.0M.MICRO", "2026.02.7", "", "2026.03.0", nil},
+		{"YYYY.0M.MICRO", "2025.12.7", "", "2026.03.0", nil},
+		{"YYYY.0M.MICRO", "2026.03.0", "", "2026.03.1", nil},
+		{"YYYY.0M.MICRO", "2026.04.2", "", "2026.04.3", nil},
+		{"YY.0M.0D", "26.03.08", "", "26.03.09", nil},
+		{"YY.0M.0D", "26.03.09", "", "26.03.09", ErrCalendarPeriodReleased},
+		{"YYYY.WW.MICRO", "2026.10.4", "", "2026.11.0", nil},
+		{"0Y.0W", "26.10", "", "26.11", nil},
+	}
+	for _, tt := range tests {
+		sc := scheme(t, tt.format)
+		cur, err := sc.parse(tt.cur)
+		if err != nil {
+			t.Fatalf("%s: parse(%q): %v", tt.format, tt.cur, err)
+		}
+		ver, err := sc.next(cur, tt.typ, now)
+		if err != tt.err {
+			t.Errorf("%s: next(%s) error = %v, want %v", tt.format, tt.cur, err, tt.err)
+			continue
+		}
+		if err == nil && sc.format(ver) != tt.want {
+			t.Errorf("%s: next(%s) = %s, want %s", tt.format, tt.cur, sc.format(ver), tt.want)
+		}
+	}
+}
+
+func TestSchemeInitial(t *testing.T) {
+	now := time.Date(2026, time.March, 9, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"semver", "0.0.1"},
+		{"build", "1"},
+		{"YYYY.MM.MICRO", "2026.3.0"},
+		{"0Y.0M.0D", "26.03.09"},
+	}
+	for _, tt := range tests {
+		sc := scheme(t, tt.format)
+		if got := sc.format(sc.initial(now)); got != tt.want {
+			t.Errorf("%s: initial = %s, want %s", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestSchemeRange(t *testing.T) {
+	tests := []struct {
+		format string
+		rng    string
+		want   string
+	}{
+		{"semver", ">=1.2.0 <2.0.0", ">=1.2.0 <2.0.0"},
+		{"build", ">=40 <42", ">=40.0.0 <42.0.0"},
+		{"YYYY.0M.MICRO", ">=2026.01.0 <2026.03.0", ">=2026.1.0 <2026.3.0"},
+		{"YYYY.0M.MICRO", ">=2026.1.0", ">=2026.1.0"},
+	}
+	for _, tt := range tests {
+		sc := scheme(t, tt.format)
+		got := sc.rng(tt.rng)
+		if got != tt.want {
+			t.Errorf("%s: rng(%q) = %q, want %q", tt.format, tt.rng, got, tt.want)
+			continue
+		}
+		if _, err := semver.ParseRange(got); err != nil {
+			t.Errorf("%s: rng(%q) = %q is not a range: %v", tt.format, tt.rng, got, err)
+		}
+	}
+}
+
+// scheme creates a scheme from a calver format or a scheme name
+func scheme(t *testing.T, format string) *Scheme {
+	name := SchemeCalver
+	if format == SchemeSemver || format == SchemeBuild {
+		name, format = format, ""
+	}
+	sc, err := newScheme(name, format)
+	if err != nil {
+		t.Fatalf("newScheme(%q, %q): %v", name, format, err)
+	}
+	return sc
+}
@@ -0,0 +1,190 @@
+package v1
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/gin-gonic/gin"
+)
+
+// valid tag name, e.g. latest, lts, canary or production
+var tagName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// tag validates a tag name
+func (r *Router) tag(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if !tagName.MatchString(name) {
+		return "", ErrInvalidTag
+	}
+	return name, nil
+}
+
+// tagged returns the version the tag of project `id` points at
+func (r *Router) tagged(id string, scope []string, name string) (semver.Version, error) {
+	var ver semver.Version
+	vers, err := r.m.Get(
+		r.key(id, scope, "tags", name),
+	)
+	if err != nil || len(vers) <= 0 || vers[0] == "" {
+		return ver, ErrTagNotFound
+	}
+	return r.version(vers[0])
+}
+
+// archived checks if version `ver` exists in the archive of project `id`
+func (r *Router) archived(id string, scope []string, ver semver.Version) bool {
+	vers, err := r.m.Get(
+		r.key(id, scope, "archive", ver.String()),
+	)
+	return err == nil && len(vers) > 0 && vers[0] != ""
+}
+
+// Tags lists tags of project `id`
+func (r *Router) Tags(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	scope, err := r.scope(c)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if _, err := r.current(id, scope); err != nil {
+		r.err(c, err)
+		return
+	}
+	keys, err := r.m.List(r.key(id, scope, "tags"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	names := []string{}
+	for _, key := range keys {
+		names = append(names, key.Dirs[len(key.Dirs)-1])
+	}
+	sort.Strings(names)
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	list := &Tags{
+		Tags: make([]*Tag, 0, len(names)),
+	}
+	for _, name := range names {
+		ver, err := r.tagged(id, scope, name)
+		if err != nil {
+			continue
+		}
+		list.Tags = append(list.Tags, &Tag{
+			Name:    name,
+			Version: sc.format(ver),
+		})
+	}
+	r.echo(c, list)
+}
+
+// SetTag points a tag of project `id` at an archived version
+func (r *Router) SetTag(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	scope, err := r.scope(c)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	name, err := r.tag(c.Param("tag"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if _, err := r.current(id, scope); err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	ver, err := sc.parse(strings.TrimSpace(c.PostForm("version")))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if !r.archived(id, scope, ver) {
+		r.err(c, ErrVersionNotFound)
+		return
+	}
+	ev := &Event{Type: "tag", Tag: name, Version: sc.format(ver)}
+	if prev, err := r.tagged(id, scope, name); err == nil {
+		ev.Previous = sc.format(prev)
+	}
+	if err := r.m.Set(
+		ver.String(),
+		r.key(id, scope, "tags", name),
+	); err != nil {
+		r.err(c, err)
+		return
+	}
+	if err := r.record(id, scope, ev); err != nil {
+		r.err(c, err)
+		return
+	}
+	r.echo(c, &Tag{Name: name, Version: sc.format(ver)})
+}
+
+// DeleteTag removes a tag of project `id`
+func (r *Router) DeleteTag(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	scope, err := r.scope(c)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	name, err := r.tag(c.Param("tag"))
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if _, err := r.current(id, scope); err != nil {
+		r.err(c, err)
+		return
+	}
+	prev, err := r.tagged(id, scope, name)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	if err := r.m.Delete(
+		r.key(id, scope, "tags", name),
+	); err != nil {
+		r.err(c, err)
+		return
+	}
+	if err := r.record(id, scope, &Event{Type: "untag", Tag: name, Previous: sc.format(prev)}); err != nil {
+		r.err(c, err)
+		return
+	}
+	c.String(http.StatusOK, "ok")
+}
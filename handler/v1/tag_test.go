@@ -0,0 +1,70 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	s, done := serve(t)
+	defer done()
+	id := s.create("version=1.0.0")
+	p := "/v1/" + id
+	s.must("POST", p, url.Values{"version": {"1.1.0"}})
+	s.must("POST", p, url.Values{"version": {"2.0.0-rc.1"}})
+
+	// latest is computed until it is set
+	if got := s.must("GET", p+"?tag=latest", nil); got != "2.0.0-rc.1" {
+		t.Errorf("computed latest = %q", got)
+	}
+	if got := s.must("POST", p+"/tags/lts", url.Values{"version": {"1.0.0"}}); got != "lts 1.0.0" {
+		t.Errorf("SetTag = %q", got)
+	}
+	if got := s.must("POST", p+"/tags/lts", url.Values{"version": {"1.1.0"}}); got != "lts 1.1.0" {
+		t.Errorf("SetTag move = %q", got)
+	}
+	s.must("POST", p+"/tags/latest", url.Values{"version": {"1.1.0"}})
+	if got := s.must("GET", p+"?tag=latest", nil); got != "1.1.0" {
+		t.Errorf("latest = %q, want 1.1.0", got)
+	}
+	if got, want := s.must("GET", p+"/tags", nil), "latest 1.1.0\nlts 1.1.0"; got != want {
+		t.Errorf("Tags = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		form   url.Values
+		want   error
+	}{
+		{"POST", p + "/tags/lts", url.Values{"version": {"3.0.0"}}, ErrVersionNotFound},
+		{"POST", p + "/tags/LTS", url.Values{"version": {"1.0.0"}}, ErrInvalidTag},
+		{"GET", p + "?tag=canary", nil, ErrTagNotFound},
+		{"DELETE", p + "/tags/canary", nil, ErrTagNotFound},
+	}
+	for _, tt := range tests {
+		if code, body := s.do(tt.method, tt.path, tt.form); code != http.StatusForbidden || body != tt.want.Error() {
+			t.Errorf("%s %s = %d %q, want %v", tt.method, tt.path, code, body, tt.want)
+		}
+	}
+
+	if got := s.must("DELETE", p+"/tags/lts", nil); got != "ok" {
+		t.Errorf("DeleteTag = %q", got)
+	}
+	if got, want := s.must("GET", p+"/tags", nil), "latest 1.1.0"; got != want {
+		t.Errorf("Tags after delete = %q, want %q", got, want)
+	}
+	var arch Archive
+	s.json("GET", p+"/history", nil, &arch)
+	var types []string
+	for _, e := range arch.Events {
+		if e.Tag == "lts" {
+			types = append(types, e.Type+" "+e.Previous+" "+e.Version)
+		}
+	}
+	if want := []string{"tag  1.0.0", "tag 1.0.0 1.1.0", "untag 1.1.0 "}; !reflect.DeepEqual(types, want) {
+		t.Errorf("lts events = %q, want %q", types, want)
+	}
+}
@@ -0,0 +1,145 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samuelngs/semver/backend"
+	"github.com/samuelngs/semver/pkg/logger"
+)
+
+// trash is the reserved record id that indexes soft deleted projects
+const trash = "trash"
+
+// exists checks if project `id` exists and has not been deleted
+func (r *Router) exists(id string) (bool, error) {
+	exists, err := r.m.Exists(r.m.Path(id, "version"))
+	if err != nil || !exists {
+		return false, err
+	}
+	deleted, err := r.deleted(id)
+	if err != nil {
+		return false, err
+	}
+	return !deleted, nil
+}
+
+// deleted checks if project `id` has been soft deleted
+func (r *Router) deleted(id string) (bool, error) {
+	vals, err := r.m.Get(
+		r.m.Path(id, "deleted"),
+	)
+	if err == backend.ErrRecordNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return len(vals) > 0 && vals[0] != "", nil
+}
+
+// purge removes every record and reservation of project `id` immediately
+func (r *Router) purge(id string) error {
+	keys, err := r.m.List(r.m.Path(id))
+	if err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		if err := r.m.Delete(keys...); err != nil {
+			return err
+		}
+	}
+	leases, err := r.m.Leases(r.m.Path(id))
+	if err != nil && err != backend.ErrNotSupported {
+		return err
+	}
+	if len(leases) > 0 {
+		keys = make([]*backend.Key, len(leases))
+		for i, l := range leases {
+			keys[i] = l.Key
+		}
+		if err := r.m.Revoke(keys...); err != nil {
+			return err
+		}
+	}
+	return r.m.Delete(r.m.Path(trash, id))
+}
+
+// reap purges soft deleted projects whose retention period has expired
+func (r *Router) reap(retention time.Duration) error {
+	keys, err := r.m.List(r.m.Path(trash))
+	if err == backend.ErrRecordNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if len(key.Dirs) == 0 {
+			continue
+		}
+		vals, err := r.m.Get(key)
+		if err != nil || len(vals) <= 0 {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, vals[0])
+		if err != nil || time.Since(at) < retention {
+			continue
+		}
+		if err := r.purge(key.Dirs[len(key.Dirs)-1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reaper runs reap on every interval until the router is closed
+func (r *Router) reaper(retention, interval time.Duration) {
+	defer r.wg.Done()
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-r.quit:
+			return
+		case <-tick.C:
+			if err := r.reap(retention); err != nil {
+				logger.Error("reaper failed", logger.Fields{"error": err.Error()})
+			}
+		}
+	}
+}
+
+// Restore brings back a soft deleted project
+func (r *Router) Restore(c *gin.Context) {
+	defer r.release(c)
+	id := c.Param("id")
+	if _, err := r.uuid(id); err != nil {
+		r.err(c, err)
+		return
+	}
+	deleted, err := r.deleted(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	} else if !deleted {
+		r.err(c, ErrProjectNotFound)
+		return
+	}
+	if err := r.m.Delete(
+		r.m.Path(id, "deleted"),
+		r.m.Path(trash, id),
+	); err != nil {
+		r.err(c, err)
+		return
+	}
+	ver, err := r.current(id, nil)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	sc, err := r.scheme(id)
+	if err != nil {
+		r.err(c, err)
+		return
+	}
+	r.echo(c, r.versioning(sc, ver))
+}
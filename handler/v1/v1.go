@@ -1,16 +1,32 @@
 package v1
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/samuelngs/semver/backend"
+	"github.com/samuelngs/semver/pkg/env"
 )
 
 const defaultVersion = "0.0.1"
 
+// default retention of soft deleted projects and reaper interval
+const (
+	defaultRetention      = 30 * 24 * time.Hour
+	defaultReaperInterval = time.Hour
+)
+
 // New create route
-func New(b backend.Client, c *gin.Engine) *Router {
+func New(m *backend.Manager, c *gin.Engine) *Router {
 
-	r := &Router{backend.New(b)}
+	r := &Router{m: m, quit: make(chan struct{})}
+
+	// a zero interval disables the reaper, projects are then only purged on
+	// request
+	if interval := env.Duration("SEMVER_REAPER_INTERVAL", defaultReaperInterval); interval > 0 {
+		r.wg.Add(1)
+		go r.reaper(env.Duration("SEMVER_RETENTION", defaultRetention), interval)
+	}
 
 	g := c.Group("/v1")
 	{
@@ -21,17 +37,150 @@ func New(b backend.Client, c *gin.Engine) *Router {
 		// GET: /v1/{project-id} or /v1/new
 		g.GET("/:id", r.Get)
 
-		// POST: /v1/{project-id}
+		// POST: /v1/{project-id} or /v1/batch
 		g.POST("/:id", r.Set)
 
 		// DELETE: /v1/{project-id}
 		g.DELETE("/:id", r.Delete)
 
+		// POST: /v1/{project-id}/restore
+		g.POST("/:id/restore", r.Restore)
+
 		// GET: /v1/{project-id}/history
 		g.GET("/:id/history", r.History)
 
 		// GET: /v1/{project-id}/bump
 		g.GET("/:id/bump", r.Bump)
+
+		// GET: /v1/{project-id}/scheme
+		g.GET("/:id/scheme", r.GetScheme)
+
+		// GET: /v1/{project-id}/channels
+		g.GET("/:id/channels", r.Channels)
+
+		// POST: /v1/{project-id}/promote
+		g.POST("/:id/promote", r.Promote)
+
+		// GET: /v1/{project-id}/tags
+		g.GET("/:id/tags", r.Tags)
+
+		// POST: /v1/{project-id}/tags/{tag}
+		g.POST("/:id/tags/:tag", r.SetTag)
+
+		// DELETE: /v1/{project-id}/tags/{tag}
+		g.DELETE("/:id/tags/:tag", r.DeleteTag)
+
+		// GET: /v1/{project-id}/resolve
+		g.GET("/:id/resolve", r.Resolve)
+
+		// POST: /v1/{project-id}/versions/{version}/yank
+		g.POST("/:id/versions/:version/yank", r.Yank)
+
+		// DELETE: /v1/{project-id}/versions/{version}/yank
+		g.DELETE("/:id/versions/:version/yank", r.Unyank)
+
+		// POST: /v1/{project-id}/versions/{version}/deprecate
+		g.POST("/:id/versions/:version/deprecate", r.Deprecate)
+
+		// DELETE: /v1/{project-id}/versions/{version}/deprecate
+		g.DELETE("/:id/versions/:version/deprecate", r.Undeprecate)
+
+		// POST: /v1/{project-id}/bump/auto
+		g.POST("/:id/bump/auto", r.AutoBump)
+
+		// GET: /v1/{project-id}/counters
+		g.GET("/:id/counters", r.Counters)
+
+		// GET: /v1/{project-id}/counters/{counter}
+		g.GET("/:id/counters/:counter", r.GetCounter)
+
+		// POST: /v1/{project-id}/counters/{counter}
+		g.POST("/:id/counters/:counter", r.Increment)
+
+		// DELETE: /v1/{project-id}/counters/{counter}
+		g.DELETE("/:id/counters/:counter", r.DeleteCounter)
+
+		// GET: /v1/{project-id}/reservations
+		g.GET("/:id/reservations", r.Reservations)
+
+		// POST: /v1/{project-id}/reservations
+		g.POST("/:id/reservations", r.Reserve)
+
+		// POST: /v1/{project-id}/reservations/{version}/commit
+		g.POST("/:id/reservations/:version/commit", r.CommitReservation)
+
+		// DELETE: /v1/{project-id}/reservations/{version}
+		g.DELETE("/:id/reservations/:version", r.DeleteReservation)
+
+		// GET: /v1/{project-id}/components
+		g.GET("/:id/components", r.Components)
+
+		// POST: /v1/{project-id}/release
+		g.POST("/:id/release", r.ReleaseComponents)
+	}
+
+	// components share the project handlers, scoped by the component name
+	cg := c.Group("/v1/:id/components/:name")
+	{
+		// GET: /v1/{project-id}/components/{name}
+		cg.GET("", r.Get)
+
+		// POST: /v1/{project-id}/components/{name}
+		cg.POST("", r.Set)
+
+		// DELETE: /v1/{project-id}/components/{name}
+		cg.DELETE("", r.DeleteComponent)
+
+		// GET: /v1/{project-id}/components/{name}/history
+		cg.GET("/history", r.History)
+
+		// GET: /v1/{project-id}/components/{name}/bump
+		cg.GET("/bump", r.Bump)
+
+		// POST: /v1/{project-id}/components/{name}/bump/auto
+		cg.POST("/bump/auto", r.AutoBump)
+
+		// GET: /v1/{project-id}/components/{name}/channels
+		cg.GET("/channels", r.Channels)
+
+		// POST: /v1/{project-id}/components/{name}/promote
+		cg.POST("/promote", r.Promote)
+
+		// GET: /v1/{project-id}/components/{name}/tags
+		cg.GET("/tags", r.Tags)
+
+		// POST: /v1/{project-id}/components/{name}/tags/{tag}
+		cg.POST("/tags/:tag", r.SetTag)
+
+		// DELETE: /v1/{project-id}/components/{name}/tags/{tag}
+		cg.DELETE("/tags/:tag", r.DeleteTag)
+
+		// GET: /v1/{project-id}/components/{name}/resolve
+		cg.GET("/resolve", r.Resolve)
+
+		// GET: /v1/{project-id}/components/{name}/reservations
+		cg.GET("/reservations", r.Reservations)
+
+		// POST: /v1/{project-id}/components/{name}/reservations
+		cg.POST("/reservations", r.Reserve)
+
+		// POST: /v1/{project-id}/components/{name}/reservations/{version}/commit
+		cg.POST("/reservations/:version/commit", r.CommitReservation)
+
+		// DELETE: /v1/{project-id}/components/{name}/reservations/{version}
+		cg.DELETE("/reservations/:version", r.DeleteReservation)
+
+		// POST: /v1/{project-id}/components/{name}/versions/{version}/yank
+		cg.POST("/versions/:version/yank", r.Yank)
+
+		// DELETE: /v1/{project-id}/components/{name}/versions/{version}/yank
+		cg.DELETE("/versions/:version/yank", r.Unyank)
+
+		// POST: /v1/{project-id}/components/{name}/versions/{version}/deprecate
+		cg.POST("/versions/:version/deprecate", r.Deprecate)
+
+		// DELETE: /v1/{project-id}/components/{name}/versions/{version}/deprecate
+		cg.DELETE("/versions/:version/deprecate", r.Undeprecate)
 	}
 	return r
 }
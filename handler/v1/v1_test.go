@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samuelngs/semver/backend"
+)
+
+// server serves the v1 routes on a temporary bolt file
+type server struct {
+	t      *testing.T
+	r      *Router
+	engine *gin.Engine
+}
+
+// serve starts the v1 router on a temporary bolt file
+func serve(t *testing.T) (*server, func()) {
+	dir, err := ioutil.TempDir("", "semver-v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := backend.New()
+	if err := m.Use(&backend.Bolt{File: filepath.Join(dir, "semver.db")}); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	s := &server{t: t, r: New(m, engine), engine: engine}
+	return s, func() {
+		s.r.Close()
+		m.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// do sends a request with an optional form and returns the status and the
+// text output
+func (s *server) do(method, path string, form url.Values) (int, string) {
+	if form == nil {
+		return s.send(method, path, "", "")
+	}
+	return s.send(method, path, "application/x-www-form-urlencoded", form.Encode())
+}
+
+// send sends a request with a body of content type typ
+func (s *server) send(method, path, typ, body string) (int, string) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if typ != "" {
+		req.Header.Set("Content-Type", typ)
+	}
+	rec := httptest.NewRecorder()
+	s.engine.ServeHTTP(rec, req)
+	return rec.Code, strings.TrimSpace(rec.Body.String())
+}
+
+// must sends a request that has to succeed and returns the text output
+func (s *server) must(method, path string, form url.Values) string {
+	s.t.Helper()
+	code, body := s.do(method, path, form)
+	if code != http.StatusOK {
+		s.t.Fatalf("%s %s = %d %s", method, path, code, body)
+	}
+	return body
+}
+
+// json sends a request for the json output and decodes it into v
+func (s *server) json(method, path string, form url.Values, v interface{}) int {
+	s.t.Helper()
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	code, body := s.do(method, path+sep+"output=json", form)
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		s.t.Fatalf("%s %s: %v: %s", method, path, err, body)
+	}
+	return code
+}
+
+// create creates a project, an empty version starts from the default
+func (s *server) create(query string) string {
+	s.t.Helper()
+	return s.must("GET", "/v1/new?"+query, nil)
+}
@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/samuelngs/semver/pkg/env"
+)
+
+// grantKey is the context key of requests granted administrator privileges
+type grantKey struct{}
+
+// Grant gives a request administrator privileges without a token, it is used
+// by in-process clients that own the storage backend
+func Grant(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), grantKey{}, true))
+}
+
+// Authorized checks if the request carries the administrator token or was
+// granted administrator privileges, administrator operations are disabled
+// when SEMVER_ADMIN_TOKEN is not configured
+func Authorized(req *http.Request) bool {
+	if granted, _ := req.Context().Value(grantKey{}).(bool); granted {
+		return true
+	}
+	token := env.Raw("SEMVER_ADMIN_TOKEN")
+	if token == "" {
+		return false
+	}
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) == 1
+}
@@ -0,0 +1,62 @@
+package api
+
+import "errors"
+
+// errs lists the errors of the api in declaration order
+var errs []error
+
+// newError creates an error of the api
+func newError(msg string) error {
+	err := errors.New(msg)
+	errs = append(errs, err)
+	return err
+}
+
+// Known returns the error of the api with message msg, nil when there is none
+func Known(msg string) error {
+	for _, err := range errs {
+		if err.Error() == msg {
+			return err
+		}
+	}
+	return nil
+}
+
+// List of error messages
+var (
+	ErrProjectNotFound         = newError("project id does not match any records in our database")
+	ErrInvalidVersioningFormat = newError("invalid semantic versioning format")
+	ErrInvalidUUID             = newError("invalid uuid")
+	ErrNoReleasableChanges     = newError("commits do not contain any releasable changes")
+	ErrInvalidRequestBody      = newError("invalid request body")
+	ErrInvalidChannel          = newError("invalid release channel name")
+	ErrChannelNotFound         = newError("release channel does not match any records in our database")
+	ErrVersionNotFound         = newError("version does not match any records in our database")
+	ErrInvalidTag              = newError("invalid tag name")
+	ErrInvalidRange            = newError("invalid semantic versioning range")
+	ErrTagNotFound             = newError("tag does not match any records in our database")
+	ErrInvalidComponent        = newError("invalid component name")
+	ErrComponentNotFound       = newError("component does not match any records in our database")
+	ErrInvalidBumpType         = newError("invalid bump type, expected major, minor or patch")
+	ErrInvalidBatch            = newError("invalid batch, expected a list of operations")
+	ErrBatchTooLarge           = newError("batch exceeds the maximum number of operations")
+	ErrInvalidOperation        = newError("invalid operation, expected bump or set")
+	ErrDuplicateOperation      = newError("project is changed more than once in the batch")
+	ErrVersionMismatch         = newError("current version does not match the expected version")
+	ErrBatchRejected           = newError("batch rejected, no operation was applied")
+	ErrInvalidScheme           = newError("invalid versioning scheme, expected semver, calver or build")
+	ErrInvalidCalverFormat     = newError("invalid calver format, e.g. YYYY.MM.MICRO or YY.0M.0D")
+	ErrCalendarPeriodReleased  = newError("a version of the current calendar period is already released")
+	ErrInvalidCounter          = newError("invalid counter name")
+	ErrCounterNotFound         = newError("counter does not match any records in our database")
+	ErrInvalidCounterReset     = newError("invalid counter reset, expected never, major or minor")
+	ErrInvalidTTL              = newError("invalid reservation ttl")
+	ErrVersionReserved         = newError("version is reserved")
+	ErrVersionReleased         = newError("version is already released")
+	ErrReservationNotFound     = newError("reservation does not match any records in our database")
+	ErrInvalidReservationToken = newError("invalid reservation token")
+
+	ErrForbidden      = newError("administrator privileges required")
+	ErrAccessDenied   = newError("access to the project is denied")
+	ErrInternalServer = newError("internal server error")
+)
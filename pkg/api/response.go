@@ -0,0 +1,319 @@
+// Package api holds the response types and error messages of the v1 api, it
+// has no dependencies so clients can use it without the server.
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Warning represent error message
+type Warning struct {
+	Error     string `json:"error" xml:"message"`
+	RequestID string `json:"request_id,omitempty" xml:"request_id,omitempty"`
+}
+
+// Versioning represents a valid semver version
+type Versioning struct {
+	Project string   `json:"project,omitempty" xml:"project,omitempty"`
+	Version string   `json:"version,omitempty" xml:"version,omitempty"`
+	Major   uint64   `json:"major" xml:"major"`
+	Minor   uint64   `json:"minor" xml:"minor"`
+	Patch   uint64   `json:"patch" xml:"patch"`
+	Build   []string `json:"build,omitempty" xml:"build,omitempty"`
+
+	Yanked          bool   `json:"yanked,omitempty" xml:"yanked,omitempty"`
+	YankReason      string `json:"yank_reason,omitempty" xml:"yank_reason,omitempty"`
+	Deprecated      bool   `json:"deprecated,omitempty" xml:"deprecated,omitempty"`
+	DeprecateReason string `json:"deprecate_reason,omitempty" xml:"deprecate_reason,omitempty"`
+
+	// Counters are the last counter values issued for the version
+	Counters map[string]int64 `json:"counters,omitempty" xml:"-"`
+}
+
+// String returns the string format of Versioning object
+func (v *Versioning) String() string {
+	if v.Project != "" {
+		return v.Project
+	}
+	var flags []string
+	if v.Yanked {
+		flags = append(flags, flag("yanked", v.YankReason))
+	}
+	if v.Deprecated {
+		flags = append(flags, flag("deprecated", v.DeprecateReason))
+	}
+	if len(flags) == 0 {
+		return v.Version
+	}
+	return fmt.Sprintf("%s (%s)", v.Version, strings.Join(flags, ", "))
+}
+
+// flag formats a version flag with its reason
+func flag(name, reason string) string {
+	if reason == "" {
+		return name
+	}
+	return name + ": " + reason
+}
+
+// Archive represents a list of semver version
+type Archive struct {
+	Versions []*Versioning `json:"versions" xml:"version"`
+	Events   []*Event      `json:"events,omitempty" xml:"event,omitempty"`
+}
+
+// String returns the string format of Archive object
+func (v *Archive) String() string {
+	var output string
+	if v.Versions == nil {
+		v.Versions = make([]*Versioning, 0)
+	}
+	for _, ver := range v.Versions {
+		output += fmt.Sprintf("%v\n", ver)
+	}
+	return output
+}
+
+// ChangelogSection represents the release notes of a commit type
+type ChangelogSection struct {
+	Type  string   `json:"type" xml:"type,attr"`
+	Title string   `json:"title" xml:"title"`
+	Notes []string `json:"notes" xml:"note"`
+}
+
+// Release represents a version bumped from a list of commits
+type Release struct {
+	Type      string              `json:"type" xml:"type"`
+	Version   *Versioning         `json:"version" xml:"version"`
+	Changelog []*ChangelogSection `json:"changelog" xml:"changelog>section"`
+}
+
+// String returns the string format of Release object
+func (v *Release) String() string {
+	output := fmt.Sprintf("%v\n", v.Version)
+	for _, s := range v.Changelog {
+		output += fmt.Sprintf("\n### %s\n\n", s.Title)
+		for _, n := range s.Notes {
+			output += fmt.Sprintf("- %s\n", n)
+		}
+	}
+	return output
+}
+
+// Channel represents a release channel of a project
+type Channel struct {
+	Name    string `json:"name" xml:"name"`
+	Version string `json:"version" xml:"version"`
+}
+
+// String returns the string format of Channel object
+func (v *Channel) String() string {
+	return fmt.Sprintf("%s %s", v.Name, v.Version)
+}
+
+// Channels represents a list of release channels
+type Channels struct {
+	Channels []*Channel `json:"channels" xml:"channel"`
+}
+
+// String returns the string format of Channels object
+func (v *Channels) String() string {
+	var output string
+	for _, ch := range v.Channels {
+		output += fmt.Sprintf("%v\n", ch)
+	}
+	return output
+}
+
+// Component represents an independently versioned component of a project
+type Component struct {
+	Name    string `json:"name" xml:"name"`
+	Version string `json:"version" xml:"version"`
+}
+
+// String returns the string format of Component object
+func (v *Component) String() string {
+	return fmt.Sprintf("%s %s", v.Name, v.Version)
+}
+
+// Components represents the aggregate view of a project and its components
+type Components struct {
+	Version    string       `json:"version" xml:"version"`
+	Components []*Component `json:"components" xml:"component"`
+}
+
+// String returns the string format of Components object
+func (v *Components) String() string {
+	var output string
+	for _, comp := range v.Components {
+		output += fmt.Sprintf("%v\n", comp)
+	}
+	return output
+}
+
+// Tag represents a named pointer at a version
+type Tag struct {
+	Name    string `json:"name" xml:"name"`
+	Version string `json:"version" xml:"version"`
+}
+
+// String returns the string format of Tag object
+func (v *Tag) String() string {
+	return fmt.Sprintf("%s %s", v.Name, v.Version)
+}
+
+// Tags represents a list of tags
+type Tags struct {
+	Tags []*Tag `json:"tags" xml:"tag"`
+}
+
+// String returns the string format of Tags object
+func (v *Tags) String() string {
+	var output string
+	for _, t := range v.Tags {
+		output += fmt.Sprintf("%v\n", t)
+	}
+	return output
+}
+
+// Event represents a change recorded in the project history
+type Event struct {
+	Type     string `json:"type" xml:"type,attr"`
+	Tag      string `json:"tag,omitempty" xml:"tag,omitempty"`
+	From     string `json:"from,omitempty" xml:"from,omitempty"`
+	Version  string `json:"version,omitempty" xml:"version,omitempty"`
+	Previous string `json:"previous,omitempty" xml:"previous,omitempty"`
+	Reason   string `json:"reason,omitempty" xml:"reason,omitempty"`
+	Counter  string `json:"counter,omitempty" xml:"counter,omitempty"`
+	Value    int64  `json:"value,omitempty" xml:"value,omitempty"`
+	Time     string `json:"time" xml:"time"`
+}
+
+// BatchResult represents the result of an operation of a batch
+type BatchResult struct {
+	Project   string `json:"project" xml:"project"`
+	Component string `json:"component,omitempty" xml:"component,omitempty"`
+	Channel   string `json:"channel,omitempty" xml:"channel,omitempty"`
+	Previous  string `json:"previous,omitempty" xml:"previous,omitempty"`
+	Version   string `json:"version,omitempty" xml:"version,omitempty"`
+	Error     string `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// String returns the string format of BatchResult object
+func (v *BatchResult) String() string {
+	name := v.Project
+	if v.Component != "" {
+		name += "/" + v.Component
+	}
+	if v.Channel != "" {
+		name += "@" + v.Channel
+	}
+	if v.Error != "" {
+		return fmt.Sprintf("%s error: %s", name, v.Error)
+	}
+	return fmt.Sprintf("%s %s -> %s", name, v.Previous, v.Version)
+}
+
+// Batch represents the results of a batch of operations
+type Batch struct {
+	Applied bool           `json:"applied" xml:"applied"`
+	Atomic  bool           `json:"atomic" xml:"atomic"`
+	Error   string         `json:"error,omitempty" xml:"error,omitempty"`
+	Results []*BatchResult `json:"results" xml:"result"`
+}
+
+// String returns the string format of Batch object
+func (v *Batch) String() string {
+	var output string
+	for _, item := range v.Results {
+		output += fmt.Sprintf("%v\n", item)
+	}
+	if v.Error != "" {
+		output += fmt.Sprintf("%s\n", v.Error)
+	}
+	return output
+}
+
+// Counter represents a named build number counter of a project
+type Counter struct {
+	Name    string `json:"name" xml:"name"`
+	Reset   string `json:"reset" xml:"reset"`
+	Value   int64  `json:"value" xml:"value"`
+	Version string `json:"version" xml:"version"`
+}
+
+// String returns the string format of Counter object
+func (v *Counter) String() string {
+	return strconv.FormatInt(v.Value, 10)
+}
+
+// Counters represents a list of counters
+type Counters struct {
+	Counters []*Counter `json:"counters" xml:"counter"`
+}
+
+// String returns the string format of Counters object
+func (v *Counters) String() string {
+	var output string
+	for _, ctr := range v.Counters {
+		output += fmt.Sprintf("%s %d\n", ctr.Name, ctr.Value)
+	}
+	return output
+}
+
+// Reservation represents a version claimed before it is released, the token
+// is only returned to the owner
+type Reservation struct {
+	Version string `json:"version" xml:"version"`
+	Owner   string `json:"owner,omitempty" xml:"owner,omitempty"`
+	Token   string `json:"token,omitempty" xml:"token,omitempty"`
+	Expires string `json:"expires" xml:"expires"`
+}
+
+// String returns the string format of Reservation object
+func (v *Reservation) String() string {
+	if v.Token != "" {
+		return fmt.Sprintf("%s %s", v.Version, v.Token)
+	}
+	if v.Owner != "" {
+		return fmt.Sprintf("%s %s (%s)", v.Version, v.Expires, v.Owner)
+	}
+	return fmt.Sprintf("%s %s", v.Version, v.Expires)
+}
+
+// Reservations represents a list of reservations
+type Reservations struct {
+	Reservations []*Reservation `json:"reservations" xml:"reservation"`
+}
+
+// String returns the string format of Reservations object
+func (v *Reservations) String() string {
+	var output string
+	for _, res := range v.Reservations {
+		output += fmt.Sprintf("%v\n", res)
+	}
+	return output
+}
+
+// Versioning schemes
+const (
+	SchemeSemver = "semver"
+	SchemeCalver = "calver"
+	SchemeBuild  = "build"
+)
+
+// Scheme represents the versioning scheme of a project
+type Scheme struct {
+	Name   string `json:"scheme" xml:"scheme"`
+	Format string `json:"format,omitempty" xml:"format,omitempty"`
+}
+
+// String returns the string format of Scheme object
+func (v *Scheme) String() string {
+	if v.Format != "" {
+		return fmt.Sprintf("%s %s", v.Name, v.Format)
+	}
+	return v.Name
+}
@@ -0,0 +1,339 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samuelngs/semver/pkg/env"
+	"gopkg.in/yaml.v2"
+)
+
+// redacted replaces secret values in printed configuration
+const redacted = "******"
+
+// Option represents a configuration setting that can be read from the
+// configuration file, the environment and the command line, in that order
+type Option struct {
+	Path   string // dotted path in the configuration file, e.g. backend.addr
+	Env    string // environment variable, e.g. SEMVER_BACKEND_ADDR
+	Flag   string // command line flag, e.g. backend-addr
+	Def    string // default value
+	Usage  string
+	Secret bool
+	Bool   bool // a bare command line flag sets the option to true
+	Check  func(string) error
+}
+
+// Options lists every setting of the semver server
+var Options = []*Option{
+	{Path: "listen", Env: "SEMVER_LISTEN", Flag: "listen", Def: ":4000", Usage: "listen address", Check: address},
+	{Path: "shutdown_timeout", Env: "SEMVER_SHUTDOWN_TIMEOUT", Flag: "shutdown-timeout", Def: "30s", Usage: "time to drain in-flight requests on shutdown", Check: duration},
+	{Path: "backend.storage", Env: "SEMVER_BACKEND_STORAGE", Flag: "backend", Def: "bolt", Usage: "storage backend {bolt, redis, cassandra, gce-datastore}", Check: oneOf("bolt", "redis", "cassandra", "gce-datastore")},
+	{Path: "backend.addr", Env: "SEMVER_BACKEND_ADDR", Flag: "backend-addr", Usage: "storage backend address"},
+	{Path: "backend.db", Env: "SEMVER_BACKEND_DB", Flag: "backend-db", Usage: "storage backend database or keyspace"},
+	{Path: "backend.retries", Env: "SEMVER_BACKEND_RETRIES", Flag: "backend-retries", Usage: "storage backend retries", Check: integer},
+	{Path: "backend.token", Env: "SEMVER_BACKEND_TOKEN", Flag: "backend-token", Usage: "storage backend credentials", Secret: true},
+	{Path: "backend.username", Env: "SEMVER_BACKEND_USERNAME", Flag: "backend-username", Usage: "storage backend username"},
+	{Path: "backend.password", Env: "SEMVER_BACKEND_PASSWORD", Flag: "backend-password", Usage: "storage backend password", Secret: true},
+	{Path: "bolt.timeout", Env: "SEMVER_BOLT_TIMEOUT", Flag: "bolt-timeout", Def: "1s", Usage: "bolt file lock timeout", Check: duration},
+	{Path: "bolt.no_sync", Env: "SEMVER_BOLT_NO_SYNC", Flag: "bolt-no-sync", Usage: "skip fsync after each bolt commit, unsafe on power loss", Bool: true, Check: boolean},
+	{Path: "bolt.mmap_size", Env: "SEMVER_BOLT_MMAP_SIZE", Flag: "bolt-mmap-size", Usage: "initial bolt mmap size in bytes", Check: integer},
+	{Path: "bolt.reaper_interval", Env: "SEMVER_BOLT_REAPER_INTERVAL", Flag: "bolt-reaper-interval", Def: "1m", Usage: "interval of the expired leases reaper, 0 disables it", Check: nonNegativeDuration},
+	{Path: "cassandra.strategy", Env: "SEMVER_CASSANDRA_STRATEGY", Flag: "cassandra-strategy", Usage: "keyspace replication strategy {SimpleStrategy, NetworkTopologyStrategy}", Check: oneOf("SimpleStrategy", "NetworkTopologyStrategy")},
+	{Path: "cassandra.replication_factor", Env: "SEMVER_CASSANDRA_REPLICATION_FACTOR", Flag: "cassandra-replication-factor", Usage: "SimpleStrategy replication factor", Check: integer},
+	{Path: "cassandra.datacenters", Env: "SEMVER_CASSANDRA_DATACENTERS", Flag: "cassandra-datacenters", Usage: "NetworkTopologyStrategy replication, e.g. dc1:3,dc2:2"},
+	{Path: "cassandra.read_consistency", Env: "SEMVER_CASSANDRA_READ_CONSISTENCY", Flag: "cassandra-read-consistency", Usage: "read consistency level", Check: oneOf(consistencies...)},
+	{Path: "cassandra.write_consistency", Env: "SEMVER_CASSANDRA_WRITE_CONSISTENCY", Flag: "cassandra-write-consistency", Usage: "write consistency level", Check: oneOf(consistencies...)},
+	{Path: "cassandra.count_ttl", Env: "SEMVER_CASSANDRA_COUNT_TTL", Flag: "cassandra-count-ttl", Def: "5m", Usage: "cache duration of the project count, 0 counts on every request", Check: nonNegativeDuration},
+	{Path: "cassandra.tls", Env: "SEMVER_CASSANDRA_TLS", Flag: "cassandra-tls", Usage: "connect to cassandra over TLS", Bool: true, Check: boolean},
+	{Path: "cassandra.tls_ca", Env: "SEMVER_CASSANDRA_TLS_CA", Flag: "cassandra-tls-ca", Usage: "cassandra CA file", Check: file},
+	{Path: "cassandra.tls_cert", Env: "SEMVER_CASSANDRA_TLS_CERT", Flag: "cassandra-tls-cert", Usage: "cassandra client certificate file", Check: file},
+	{Path: "cassandra.tls_key", Env: "SEMVER_CASSANDRA_TLS_KEY", Flag: "cassandra-tls-key", Usage: "cassandra client key file", Check: file},
+	{Path: "cassandra.tls_verify", Env: "SEMVER_CASSANDRA_TLS_VERIFY", Flag: "cassandra-tls-verify", Usage: "verify cassandra host names", Bool: true, Check: boolean},
+	{Path: "redis.mode", Env: "SEMVER_REDIS_MODE", Flag: "redis-mode", Def: "standalone", Usage: "redis deployment {standalone, sentinel, cluster}", Check: oneOf("standalone", "sentinel", "cluster")},
+	{Path: "redis.master", Env: "SEMVER_REDIS_MASTER", Flag: "redis-master", Usage: "sentinel master name"},
+	{Path: "redis.prefix", Env: "SEMVER_REDIS_PREFIX", Flag: "redis-prefix", Usage: "redis key prefix"},
+	{Path: "redis.tls", Env: "SEMVER_REDIS_TLS", Flag: "redis-tls", Usage: "connect to redis over TLS, standalone mode only", Bool: true, Check: boolean},
+	{Path: "redis.tls_ca", Env: "SEMVER_REDIS_TLS_CA", Flag: "redis-tls-ca", Usage: "redis CA file", Check: file},
+	{Path: "redis.migrate", Env: "SEMVER_REDIS_MIGRATE", Flag: "redis-migrate", Usage: "migrate legacy semver:db: keys on startup", Bool: true, Check: boolean},
+	{Path: "datastore.namespace", Env: "SEMVER_DATASTORE_NAMESPACE", Flag: "datastore-namespace", Usage: "datastore namespace"},
+	{Path: "datastore.migrate", Env: "SEMVER_DATASTORE_MIGRATE", Flag: "datastore-migrate", Usage: "migrate legacy JSON blob projects on startup", Bool: true, Check: boolean},
+	{Path: "tls.cert", Env: "SEMVER_TLS_CERT", Flag: "tls-cert", Usage: "TLS certificate file", Check: file},
+	{Path: "tls.key", Env: "SEMVER_TLS_KEY", Flag: "tls-key", Usage: "TLS private key file", Check: file},
+	{Path: "tls.min_version", Env: "SEMVER_TLS_MIN_VERSION", Flag: "tls-min-version", Def: "1.2", Usage: "minimum TLS version {1.0, 1.1, 1.2, 1.3}", Check: oneOf("1.0", "1.1", "1.2", "1.3")},
+	{Path: "tls.ciphers", Env: "SEMVER_TLS_CIPHERS", Flag: "tls-ciphers", Def: "modern", Usage: "TLS cipher policy {modern, compatible} or comma separated cipher suite names"},
+	{Path: "tls.reload_interval", Env: "SEMVER_TLS_RELOAD_INTERVAL", Flag: "tls-reload-interval", Def: "30s", Usage: "interval to check certificate files for changes", Check: duration},
+	{Path: "tls.client_ca", Env: "SEMVER_TLS_CLIENT_CA", Flag: "tls-client-ca", Usage: "CA file to verify client certificates", Check: file},
+	{Path: "tls.client_auth", Env: "SEMVER_TLS_CLIENT_AUTH", Flag: "tls-client-auth", Def: "require", Usage: "client certificate policy {request, require}", Check: oneOf("request", "require")},
+	{Path: "tls.permissions", Env: "SEMVER_TLS_PERMISSIONS", Flag: "tls-permissions", Usage: "YAML file mapping client certificate subjects to project permissions", Check: file},
+	{Path: "auth.admin_token", Env: "SEMVER_ADMIN_TOKEN", Flag: "admin-token", Usage: "administrator bearer token", Secret: true},
+	{Path: "cors.origins", Env: "SEMVER_CORS_ORIGINS", Flag: "cors-origins", Usage: "comma separated list of allowed CORS origins"},
+	{Path: "rate_limit.requests", Env: "SEMVER_RATE_LIMIT", Flag: "rate-limit", Usage: "requests allowed per client and period, 0 disables rate limiting", Check: integer},
+	{Path: "rate_limit.period", Env: "SEMVER_RATE_PERIOD", Flag: "rate-period", Def: "1s", Usage: "rate limit period", Check: duration},
+	{Path: "retention", Env: "SEMVER_RETENTION", Flag: "retention", Def: "720h", Usage: "retention of deleted projects", Check: duration},
+	{Path: "reaper_interval", Env: "SEMVER_REAPER_INTERVAL", Flag: "reaper-interval", Def: "1h", Usage: "interval of the deleted projects reaper, 0 disables it", Check: nonNegativeDuration},
+	{Path: "reservation.ttl", Env: "SEMVER_RESERVATION_TTL", Flag: "reservation-ttl", Def: "1h", Usage: "default ttl of version reservations", Check: duration},
+	{Path: "reservation.max_ttl", Env: "SEMVER_RESERVATION_MAX_TTL", Flag: "reservation-max-ttl", Def: "168h", Usage: "maximum ttl of version reservations", Check: duration},
+	{Path: "metrics.enabled", Env: "SEMVER_METRICS", Flag: "metrics", Def: "false", Usage: "expose prometheus metrics", Bool: true, Check: boolean},
+	{Path: "metrics.path", Env: "SEMVER_METRICS_PATH", Flag: "metrics-path", Def: "/metrics", Usage: "prometheus metrics path"},
+	{Path: "metrics.buckets", Env: "SEMVER_METRICS_BUCKETS", Flag: "metrics-buckets", Usage: "comma separated request latency buckets in seconds", Check: floats},
+	{Path: "metrics.count_interval", Env: "SEMVER_METRICS_COUNT_INTERVAL", Flag: "metrics-count-interval", Def: "1m", Usage: "interval between counts of the projects gauge", Check: duration},
+	{Path: "access_log", Env: "SEMVER_ACCESS_LOG", Flag: "access-log", Def: "true", Usage: "write structured access log", Bool: true, Check: boolean},
+}
+
+// cassandra consistency levels
+var consistencies = []string{"ANY", "ONE", "TWO", "THREE", "QUORUM", "ALL", "LOCAL_QUORUM", "EACH_QUORUM", "LOCAL_ONE"}
+
+// Config represents the effective configuration keyed by environment variable
+type Config struct {
+	vals map[string]string
+}
+
+// Load reads the configuration file, environment variables and command line
+// flags and validates the result. The configuration file is taken from the
+// -config flag or SEMVER_CONFIG.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("semver", flag.ContinueOnError)
+	path := fs.String("config", env.Raw("SEMVER_CONFIG"), "configuration file (yaml, or toml with a .toml extension)")
+	flags := make(map[string]func() string, len(Options))
+	for _, o := range Options {
+		if o.Bool {
+			b := fs.Bool(o.Flag, false, o.Usage)
+			flags[o.Env] = func() string { return strconv.FormatBool(*b) }
+			continue
+		}
+		str := fs.String(o.Flag, "", o.Usage)
+		flags[o.Env] = func() string { return *str }
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	c := &Config{vals: make(map[string]string, len(Options))}
+	for _, o := range Options {
+		c.vals[o.Env] = o.Def
+	}
+	if *path != "" {
+		if err := c.file(*path); err != nil {
+			return nil, err
+		}
+	}
+	for _, o := range Options {
+		if v, ok := os.LookupEnv(o.Env); ok && v != "" {
+			c.vals[o.Env] = v
+		}
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for _, o := range Options {
+		if set[o.Flag] {
+			c.vals[o.Env] = flags[o.Env]()
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// file reads values from a yaml configuration file, or a toml file when the
+// file name ends with .toml
+func (c *Config) file(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %v", err)
+	}
+	var doc map[interface{}]interface{}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		doc, err = parseTOML(b)
+	} else {
+		err = yaml.Unmarshal(b, &doc)
+	}
+	if err != nil {
+		return fmt.Errorf("config: %s: %v", path, err)
+	}
+	known := make(map[string]*Option, len(Options))
+	for _, o := range Options {
+		known[o.Path] = o
+	}
+	return walk("", doc, func(p string, v interface{}) error {
+		o, ok := known[p]
+		if !ok {
+			return fmt.Errorf("config: %s: unknown setting %q", path, p)
+		}
+		switch t := v.(type) {
+		case nil:
+			c.vals[o.Env] = ""
+		case []interface{}:
+			parts := make([]string, len(t))
+			for i, s := range t {
+				parts[i] = fmt.Sprint(s)
+			}
+			c.vals[o.Env] = strings.Join(parts, ",")
+		default:
+			c.vals[o.Env] = fmt.Sprint(t)
+		}
+		return nil
+	})
+}
+
+// walk visits every leaf of a yaml or toml document with its dotted path
+func walk(prefix string, doc map[interface{}]interface{}, fn func(string, interface{}) error) error {
+	for k, v := range doc {
+		p := fmt.Sprint(k)
+		if prefix != "" {
+			p = prefix + "." + p
+		}
+		if m, ok := v.(map[interface{}]interface{}); ok {
+			if err := walk(p, m, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(p, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks every configured value
+func (c *Config) Validate() error {
+	var errs []string
+	for _, o := range Options {
+		v := c.vals[o.Env]
+		if v == "" || o.Check == nil {
+			continue
+		}
+		if err := o.Check(v); err != nil {
+			errs = append(errs, fmt.Sprintf("%s (%s): %v", o.Path, o.Env, err))
+		}
+	}
+	if (c.Get("SEMVER_TLS_CERT") == "") != (c.Get("SEMVER_TLS_KEY") == "") {
+		errs = append(errs, "tls: both tls.cert and tls.key must be set")
+	}
+	if c.Get("SEMVER_TLS_CERT") == "" && (c.Get("SEMVER_TLS_CLIENT_CA") != "" || c.Get("SEMVER_TLS_PERMISSIONS") != "") {
+		errs = append(errs, "tls: tls.client_ca and tls.permissions require tls.cert and tls.key")
+	}
+	if c.Get("SEMVER_TLS_PERMISSIONS") != "" && c.Get("SEMVER_TLS_CLIENT_CA") == "" {
+		errs = append(errs, "tls: tls.permissions requires tls.client_ca")
+	}
+	if len(errs) > 0 {
+		return errors.New("invalid configuration:\n  " + strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// Get returns the value of an environment variable name
+func (c *Config) Get(name string) string {
+	return c.vals[name]
+}
+
+// Export sets every configured value as environment variable so that the
+// server and storage backends pick them up through pkg/env
+func (c *Config) Export() error {
+	for _, o := range Options {
+		if v := c.vals[o.Env]; v != "" {
+			if err := env.Set(o.Env, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Print returns the effective configuration in yaml format with secrets redacted
+func (c *Config) Print() (string, error) {
+	doc := make(map[string]interface{})
+	for _, o := range Options {
+		v := c.vals[o.Env]
+		if o.Secret && v != "" {
+			v = redacted
+		}
+		parts := strings.Split(o.Path, ".")
+		m := doc
+		for _, p := range parts[:len(parts)-1] {
+			if _, ok := m[p]; !ok {
+				m[p] = make(map[string]interface{})
+			}
+			m = m[p].(map[string]interface{})
+		}
+		m[parts[len(parts)-1]] = v
+	}
+	b, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(b[:]), nil
+}
+
+func address(v string) error {
+	if _, _, err := net.SplitHostPort(v); err != nil {
+		return err
+	}
+	return nil
+}
+
+func oneOf(vals ...string) func(string) error {
+	return func(v string) error {
+		for _, s := range vals {
+			if v == s {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(vals, ", "))
+	}
+}
+
+func integer(v string) error {
+	if i, err := strconv.Atoi(v); err != nil || i < 0 {
+		return errors.New("must be a non-negative integer")
+	}
+	return nil
+}
+
+func boolean(v string) error {
+	if _, err := strconv.ParseBool(v); err != nil {
+		return errors.New("must be true or false")
+	}
+	return nil
+}
+
+func duration(v string) error {
+	if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+		return errors.New("must be a positive duration, e.g. 30s or 1h")
+	}
+	return nil
+}
+
+func nonNegativeDuration(v string) error {
+	if d, err := time.ParseDuration(v); err != nil || d < 0 {
+		return errors.New("must be a non-negative duration, e.g. 0 or 1m")
+	}
+	return nil
+}
+
+func floats(v string) error {
+	for _, p := range strings.Split(v, ",") {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(p), 64); err != nil {
+			return fmt.Errorf("%q is not a number", p)
+		}
+	}
+	return nil
+}
+
+func file(v string) error {
+	if _, err := os.Stat(v); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	doc, err := parseTOML([]byte(`# semver server
+listen = ":4000"   # all interfaces
+
+[backend]
+storage = "redis"
+addr = 'localhost:6379'
+db = 0
+"retries" = 1_000
+
+[bolt]
+no_sync = true
+timeout = "1s"
+file = "caf\u00e9 \"\U0001F600\" \\b\f\r\n"
+
+[cors]
+origins = [
+  "https://a.example.com", # dashboard
+  "https://b.example.com",
+]
+
+[metrics]
+buckets = [0.1, 1.5e1, 3]
+tls = { cert = "a.crt", key = "a\tb" }
+rate_limit.period = "2s"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[interface{}]interface{}{
+		"listen": ":4000",
+		"backend": map[interface{}]interface{}{
+			"storage": "redis",
+			"addr":    "localhost:6379",
+			"db":      int64(0),
+			"retries": int64(1000),
+		},
+		"bolt": map[interface{}]interface{}{
+			"no_sync": true,
+			"timeout": "1s",
+			"file":    "caf\u00e9 \"\U0001F600\" \\b\f\r\n",
+		},
+		"cors": map[interface{}]interface{}{
+			"origins": []interface{}{"https://a.example.com", "https://b.example.com"},
+		},
+		"metrics": map[interface{}]interface{}{
+			"buckets": []interface{}{0.1, 15.0, int64(3)},
+			"tls": map[interface{}]interface{}{
+				"cert": "a.crt",
+				"key":  "a\tb",
+			},
+			"rate_limit": map[interface{}]interface{}{
+				"period": "2s",
+			},
+		},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("parseTOML = %#v\nwant %#v", doc, want)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		doc string
+		err string
+	}{
+		{"listen", "line 1: expected = after key"},
+		{"listen =", "line 1: missing value"},
+		{"a = 1\na = 2", "line 2: key \"a\" is defined twice"},
+		{"[a]\nb = 1\n[a]", "line 3: table \"a\" is defined twice"},
+		{"a = 1\n[a]", "line 2: key \"a\" is not a table"},
+		{"a = \"open", "line 1: unterminated string"},
+		{"a = \"\"\"multi\"\"\"", "line 1: multi-line strings are not supported"},
+		{"[[servers]]", "line 1: arrays of tables are not supported"},
+		{"a = 1979-05-27", "line 1: unsupported value"},
+		{"a = 010", "line 1: unsupported value"},
+		{"a = 1 b = 2", "line 1: expected end of line"},
+		{"a = [1, 2", "line 1: expected , or ] in array"},
+		{"\n\n# comment\na = \"\\q\"", "line 4: invalid escape"},
+		{`a = "\x41"`, "line 1: invalid escape"},
+		{`a = "\a"`, "line 1: invalid escape"},
+		{`a = "\101"`, "line 1: invalid escape"},
+		{`a = "\u00e"`, "line 1: invalid escape"},
+		{`a = "\U0001F60"`, "line 1: invalid escape"},
+		{`a = "\`, "line 1: unterminated string"},
+	}
+	for _, tt := range tests {
+		_, err := parseTOML([]byte(tt.doc))
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("parseTOML(%q) error = %v, want %q", tt.doc, err, tt.err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "semver-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	toml := filepath.Join(dir, "semver.toml")
+	if err := ioutil.WriteFile(toml, []byte("[bolt]\ntimeout = \"2s\"\n[backend]\naddr = \"file.db\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	yml := filepath.Join(dir, "semver.yaml")
+	if err := ioutil.WriteFile(yml, []byte("backend:\n  addr: file.db\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args []string
+		want map[string]string
+	}{
+		{
+			[]string{"--bolt-no-sync", "--metrics=false"},
+			map[string]string{"SEMVER_BOLT_NO_SYNC": "true", "SEMVER_METRICS": "false", "SEMVER_ACCESS_LOG": "true"},
+		},
+		{
+			[]string{"--access-log=false", "--listen", ":5000"},
+			map[string]string{"SEMVER_ACCESS_LOG": "false", "SEMVER_LISTEN": ":5000"},
+		},
+		{
+			[]string{"--config", toml},
+			map[string]string{"SEMVER_BACKEND_ADDR": "file.db", "SEMVER_BOLT_TIMEOUT": "2s"},
+		},
+		{
+			[]string{"--config", yml, "--backend-addr", "flag.db"},
+			map[string]string{"SEMVER_BACKEND_ADDR": "flag.db"},
+		},
+		{
+			[]string{"--bolt-reaper-interval", "0"},
+			map[string]string{"SEMVER_BOLT_REAPER_INTERVAL": "0"},
+		},
+		{
+			[]string{"--reaper-interval", "0"},
+			map[string]string{"SEMVER_REAPER_INTERVAL": "0"},
+		},
+	}
+	for _, tt := range tests {
+		c, err := Load(tt.args)
+		if err != nil {
+			t.Errorf("Load(%v): %v", tt.args, err)
+			continue
+		}
+		for k, v := range tt.want {
+			if got := c.Get(k); got != v {
+				t.Errorf("Load(%v): %s = %q, want %q", tt.args, k, got, v)
+			}
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := [][]string{
+		{"--bolt-reaper-interval", "-1s"},
+		{"--bolt-timeout", "0"},
+		{"--listen", ":5000", "--bolt-no-sync=maybe"},
+	}
+	for _, args := range tests {
+		if _, err := Load(args); err == nil {
+			t.Errorf("Load(%v) expected an error", args)
+		}
+	}
+}
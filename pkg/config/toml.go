@@ -0,0 +1,377 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// decimal integer of a toml document, leading zeros are not allowed
+var tomlDecimal = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`)
+
+// characters of bare toml keys
+const tomlBareChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-"
+
+// tomlParser reads the subset of toml used by configuration files: tables,
+// inline tables, dotted and quoted keys, single line strings, integers,
+// floats, booleans and arrays. Multi-line strings, dates and arrays of
+// tables are rejected.
+type tomlParser struct {
+	s    string
+	pos  int
+	line int
+
+	// defined holds the paths of the [table] headers
+	defined map[string]bool
+}
+
+// parseTOML parses a toml document into the same form as a yaml document
+func parseTOML(b []byte) (map[interface{}]interface{}, error) {
+	p := &tomlParser{s: string(b), line: 1, defined: make(map[string]bool)}
+	doc := make(map[interface{}]interface{})
+	table := doc
+	for {
+		p.blank()
+		if p.eof() {
+			return doc, nil
+		}
+		if p.peek() == '[' {
+			if strings.HasPrefix(p.s[p.pos:], "[[") {
+				return nil, p.errorf("arrays of tables are not supported")
+			}
+			p.pos++
+			p.space()
+			keys, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			p.space()
+			if !p.consume(']') {
+				return nil, p.errorf("expected ] after table name")
+			}
+			name := strings.Join(keys, ".")
+			if p.defined[name] {
+				return nil, p.errorf("table %q is defined twice", name)
+			}
+			p.defined[name] = true
+			if table, err = p.table(doc, keys); err != nil {
+				return nil, err
+			}
+		} else if err := p.pair(table); err != nil {
+			return nil, err
+		}
+		if err := p.end(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// pair reads a `key = value` pair into table
+func (p *tomlParser) pair(table map[interface{}]interface{}) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.space()
+	if !p.consume('=') {
+		return p.errorf("expected = after key %q", strings.Join(keys, "."))
+	}
+	p.space()
+	val, err := p.value()
+	if err != nil {
+		return err
+	}
+	t, err := p.table(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, ok := t[last]; ok {
+		return p.errorf("key %q is defined twice", strings.Join(keys, "."))
+	}
+	t[last] = val
+	return nil
+}
+
+// table returns the nested table of keys, missing tables are created
+func (p *tomlParser) table(root map[interface{}]interface{}, keys []string) (map[interface{}]interface{}, error) {
+	t := root
+	for _, k := range keys {
+		switch v := t[k].(type) {
+		case nil:
+			next := make(map[interface{}]interface{})
+			t[k] = next
+			t = next
+		case map[interface{}]interface{}:
+			t = v
+		default:
+			return nil, p.errorf("key %q is not a table", k)
+		}
+	}
+	return t, nil
+}
+
+// key reads a dotted key
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		var k string
+		switch p.peek() {
+		case '"':
+			s, err := p.basic()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		case '\'':
+			s, err := p.literal()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		default:
+			start := p.pos
+			for !p.eof() && strings.IndexByte(tomlBareChars, p.peek()) >= 0 {
+				p.pos++
+			}
+			if k = p.s[start:p.pos]; k == "" {
+				return nil, p.errorf("invalid key")
+			}
+		}
+		keys = append(keys, k)
+		p.space()
+		if !p.consume('.') {
+			return keys, nil
+		}
+		p.space()
+	}
+}
+
+// value reads a string, number, boolean, array or inline table
+func (p *tomlParser) value() (interface{}, error) {
+	switch p.peek() {
+	case '"':
+		if strings.HasPrefix(p.s[p.pos:], `"""`) {
+			return nil, p.errorf("multi-line strings are not supported")
+		}
+		return p.basic()
+	case '\'':
+		if strings.HasPrefix(p.s[p.pos:], "'''") {
+			return nil, p.errorf("multi-line strings are not supported")
+		}
+		return p.literal()
+	case '[':
+		return p.array()
+	case '{':
+		return p.inline()
+	}
+	start := p.pos
+	for !p.eof() && strings.IndexByte(" \t\r\n,]}#", p.peek()) < 0 {
+		p.pos++
+	}
+	tok := p.s[start:p.pos]
+	switch {
+	case tok == "true":
+		return true, nil
+	case tok == "false":
+		return false, nil
+	case tomlDecimal.MatchString(tok):
+		return strconv.ParseInt(strings.Replace(tok, "_", "", -1), 10, 64)
+	case strings.HasPrefix(tok, "0x"), strings.HasPrefix(tok, "0o"), strings.HasPrefix(tok, "0b"):
+		if n, err := strconv.ParseInt(tok, 0, 64); err == nil {
+			return n, nil
+		}
+	case tok == "inf", tok == "+inf", tok == "-inf", tok == "nan", tok == "+nan", tok == "-nan":
+		f, _ := strconv.ParseFloat(tok, 64)
+		return f, nil
+	case strings.ContainsAny(tok, ".eE") && !strings.ContainsAny(tok, ":Tt"):
+		if f, err := strconv.ParseFloat(tok, 64); err == nil {
+			return f, nil
+		}
+	}
+	if tok == "" {
+		return nil, p.errorf("missing value")
+	}
+	return nil, p.errorf("unsupported value %q", tok)
+}
+
+// basic reads a double quoted string with escapes
+func (p *tomlParser) basic() (string, error) {
+	start := p.pos
+	p.pos++
+	for !p.eof() && p.peek() != '"' && p.peek() != '\n' {
+		if p.peek() == '\\' {
+			p.pos++
+			if err := p.escape(); err != nil {
+				return "", err
+			}
+			continue
+		}
+		p.pos++
+	}
+	if !p.consume('"') {
+		return "", p.errorf("unterminated string")
+	}
+	s, err := strconv.Unquote(p.s[start:p.pos])
+	if err != nil {
+		return "", p.errorf("invalid escape in string %s", p.s[start:p.pos])
+	}
+	return s, nil
+}
+
+// escape skips the escape sequence following a backslash, toml only knows
+// \b \t \n \f \r \" \\ \uXXXX and \UXXXXXXXX while strconv.Unquote would
+// also accept go escapes such as \x41 or \101
+func (p *tomlParser) escape() error {
+	if p.eof() {
+		return p.errorf("unterminated string")
+	}
+	c, digits := p.peek(), 0
+	switch c {
+	case 'b', 't', 'n', 'f', 'r', '"', '\\':
+	case 'u':
+		digits = 4
+	case 'U':
+		digits = 8
+	default:
+		return p.errorf("invalid escape %q in string", "\\"+string(c))
+	}
+	p.pos++
+	for i := 0; i < digits; i++ {
+		if h := p.peek(); !('0' <= h && h <= '9' || 'a' <= h && h <= 'f' || 'A' <= h && h <= 'F') {
+			return p.errorf("invalid escape in string, \\%c needs %d hex digits", c, digits)
+		}
+		p.pos++
+	}
+	return nil
+}
+
+// literal reads a single quoted string, it has no escapes
+func (p *tomlParser) literal() (string, error) {
+	p.pos++
+	start := p.pos
+	for !p.eof() && p.peek() != '\'' && p.peek() != '\n' {
+		p.pos++
+	}
+	s := p.s[start:p.pos]
+	if !p.consume('\'') {
+		return "", p.errorf("unterminated string")
+	}
+	return s, nil
+}
+
+// array reads an array, it may span several lines
+func (p *tomlParser) array() ([]interface{}, error) {
+	p.pos++
+	vals := []interface{}{}
+	for {
+		p.blank()
+		if p.consume(']') {
+			return vals, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, v)
+		p.blank()
+		if !p.consume(',') {
+			p.blank()
+			if !p.consume(']') {
+				return nil, p.errorf("expected , or ] in array")
+			}
+			return vals, nil
+		}
+	}
+}
+
+// inline reads an inline table, e.g. { cert = "a.crt", key = "a.key" }
+func (p *tomlParser) inline() (map[interface{}]interface{}, error) {
+	p.pos++
+	t := make(map[interface{}]interface{})
+	p.space()
+	if p.consume('}') {
+		return t, nil
+	}
+	for {
+		p.space()
+		if err := p.pair(t); err != nil {
+			return nil, err
+		}
+		p.space()
+		if p.consume('}') {
+			return t, nil
+		}
+		if !p.consume(',') {
+			return nil, p.errorf("expected , or } in inline table")
+		}
+	}
+}
+
+// end checks that nothing but a comment follows on the line
+func (p *tomlParser) end() error {
+	p.space()
+	if p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.pos++
+		}
+	}
+	p.consume('\r')
+	if p.consume('\n') {
+		p.line++
+	} else if !p.eof() {
+		return p.errorf("expected end of line")
+	}
+	return nil
+}
+
+// space skips spaces and tabs
+func (p *tomlParser) space() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// blank skips whitespace, new lines and comments
+func (p *tomlParser) blank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+// consume skips the next byte when it is c
+func (p *tomlParser) consume(c byte) bool {
+	if p.eof() || p.peek() != c {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
@@ -3,6 +3,8 @@ package env
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Set env
@@ -57,3 +59,56 @@ func Int(name string, defs ...int) int {
 	}
 	return i
 }
+
+// Duration to read environment key and return value in time.Duration format
+func Duration(name string, defs ...time.Duration) time.Duration {
+	var def time.Duration
+	for _, d := range defs {
+		def = d
+		break
+	}
+	v := Raw(name)
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+// Bool to read environment key and return value in bool format
+func Bool(name string, defs ...bool) bool {
+	var def bool
+	for _, d := range defs {
+		def = d
+		break
+	}
+	v := Raw(name)
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// F64s to read environment key and return comma separated values in []float64 format
+func F64s(name string, defs ...[]float64) []float64 {
+	var def []float64
+	for _, d := range defs {
+		def = d
+		break
+	}
+	v := Raw(name)
+	if v == "" {
+		return def
+	}
+	parts := strings.Split(v, ",")
+	res := make([]float64, len(parts))
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return def
+		}
+		res[i] = f
+	}
+	return res
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satori/go.uuid"
+)
+
+// RequestIDHeader is the header carrying the request id
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the gin context key of the request id
+const requestIDKey = "request_id"
+
+// Fields represents structured log fields
+type Fields map[string]interface{}
+
+var (
+	mu  sync.Mutex
+	out io.Writer = os.Stdout
+)
+
+// SetOutput sets the log destination
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	out = w
+}
+
+// Log writes a json log line
+func Log(level, msg string, fields Fields) {
+	entry := make(Fields, len(fields)+3)
+	for k, v := range fields {
+		entry[k] = v
+	}
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = level
+	entry["msg"] = msg
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	out.Write(append(b, '\n'))
+}
+
+// Info writes an info log line
+func Info(msg string, fields Fields) {
+	Log("info", msg, fields)
+}
+
+// Error writes an error log line
+func Error(msg string, fields Fields) {
+	Log("error", msg, fields)
+}
+
+// RequestID returns the request id of the context, the id is taken from
+// the X-Request-ID header or generated when the header is missing
+func RequestID(c *gin.Context) string {
+	if v, ok := c.Get(requestIDKey); ok {
+		if id, ok := v.(string); ok {
+			return id
+		}
+	}
+	id := c.Request.Header.Get(RequestIDHeader)
+	if id == "" || len(id) > 128 {
+		id = uuid.NewV4().String()
+	}
+	c.Set(requestIDKey, id)
+	c.Header(RequestIDHeader, id)
+	return id
+}
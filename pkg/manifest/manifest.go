@@ -0,0 +1,301 @@
+// Package manifest reads and writes the version of project manifest files.
+// Editors only replace the bytes of the version value so the formatting,
+// comments and key order of the files are kept.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// List of error messages
+var (
+	ErrUnknownFormat = errors.New("unknown manifest format")
+	ErrKeyNotFound   = errors.New("version key not found")
+)
+
+// Formats and their default version keys
+var defaults = map[string]string{
+	"json": "version",
+	"yaml": "version",
+	"toml": "package.version",
+	"xml":  "project/version",
+	"go":   "Version",
+	"text": "",
+}
+
+// Known are the manifest files detected in a directory when none are configured
+var Known = []string{"package.json", "Chart.yaml", "Cargo.toml", "pom.xml", "VERSION"}
+
+// File represents a manifest file
+type File struct {
+	Path   string
+	Format string
+	Key    string
+}
+
+// Parse parses a `path[:format[:key]]` file spec, the format is derived from
+// the file extension when omitted
+func Parse(spec string) (*File, error) {
+	parts := strings.SplitN(spec, ":", 3)
+	f := &File{Path: parts[0]}
+	if len(parts) > 1 && parts[1] != "" {
+		f.Format = parts[1]
+	} else {
+		f.Format = format(f.Path)
+	}
+	key, ok := defaults[f.Format]
+	if !ok {
+		return nil, fmt.Errorf("%s: %v %q", f.Path, ErrUnknownFormat, f.Format)
+	}
+	f.Key = key
+	if len(parts) > 2 && parts[2] != "" {
+		f.Key = parts[2]
+	}
+	return f, nil
+}
+
+// format derives the manifest format from the file name
+func format(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	case ".xml":
+		return "xml"
+	case ".go":
+		return "go"
+	}
+	return "text"
+}
+
+// Read returns the version of the file
+func (f *File) Read() (string, error) {
+	b, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return "", err
+	}
+	start, end, err := f.locate(b)
+	if err != nil {
+		return "", err
+	}
+	return string(b[start:end]), nil
+}
+
+// Write sets the version of the file and reports whether it changed, plain
+// text files are created when they do not exist
+func (f *File) Write(version string) (bool, error) {
+	b, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) && f.Format == "text" {
+		return true, ioutil.WriteFile(f.Path, []byte(version+"\n"), 0644)
+	} else if err != nil {
+		return false, err
+	}
+	start, end, err := f.locate(b)
+	if err != nil {
+		return false, err
+	}
+	if string(b[start:end]) == version {
+		return false, nil
+	}
+	out := make([]byte, 0, len(b)+len(version))
+	out = append(out, b[:start]...)
+	out = append(out, version...)
+	out = append(out, b[end:]...)
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return false, err
+	}
+	return true, ioutil.WriteFile(f.Path, out, info.Mode())
+}
+
+// locate returns the byte range of the version value
+func (f *File) locate(b []byte) (int, int, error) {
+	var start, end int
+	var err error
+	switch f.Format {
+	case "json":
+		start, end, err = locateJSON(b, f.Key)
+	case "yaml":
+		start, end, err = locateYAML(b, f.Key)
+	case "toml":
+		start, end, err = locateTOML(b, f.Key)
+	case "xml":
+		start, end, err = locateXML(b, f.Key)
+	case "go":
+		start, end, err = locateGo(b, f.Key)
+	case "text":
+		start, end = locateText(b)
+	default:
+		err = ErrUnknownFormat
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %v", f.Path, err)
+	}
+	return start, end, nil
+}
+
+// locateJSON finds a top level string field
+func locateJSON(b []byte, key string) (int, int, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	depth := 0
+	expectKey := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return 0, 0, ErrKeyNotFound
+		} else if err != nil {
+			return 0, 0, err
+		}
+		switch t := tok.(type) {
+		case json.Delim:
+			if t == '{' || t == '[' {
+				depth++
+				expectKey = t == '{' && depth == 1
+			} else {
+				depth--
+				expectKey = depth == 1
+			}
+			continue
+		case string:
+			if depth == 1 && expectKey && t == key {
+				// the value starts after the separator following the key
+				pos := int(dec.InputOffset())
+				for pos < len(b) && (b[pos] == ':' || b[pos] == ' ' || b[pos] == '\t' || b[pos] == '\r' || b[pos] == '\n') {
+					pos++
+				}
+				if _, err := dec.Token(); err != nil {
+					return 0, 0, err
+				}
+				end := int(dec.InputOffset())
+				if pos >= end || b[pos] != '"' {
+					return 0, 0, fmt.Errorf("%s is not a string", key)
+				}
+				return pos + 1, end - 1, nil
+			}
+		}
+		if depth == 1 {
+			expectKey = !expectKey
+		}
+	}
+}
+
+// locateYAML finds a top level scalar, quotes and trailing comments are kept
+func locateYAML(b []byte, key string) (int, int, error) {
+	re := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(key) + `:[ \t]*(?:"([^"\n]*)"|'([^'\n]*)'|([^\s#'"][^#\n]*?))[ \t]*(?:#.*)?$`)
+	return submatch(re, b)
+}
+
+// locateTOML finds `key = "value"` in a table, `package.version` is the
+// version key of the [package] table
+func locateTOML(b []byte, key string) (int, int, error) {
+	table, name := "", key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		table, name = key[:i], key[i+1:]
+	}
+	header := regexp.MustCompile(`^\s*\[\s*([^\]]+?)\s*\]`)
+	field := regexp.MustCompile(`^\s*` + regexp.QuoteMeta(name) + `\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	current := ""
+	offset := 0
+	for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+		if m := header.FindSubmatch(line); m != nil {
+			current = string(m[1])
+		} else if current == table {
+			if m := field.FindSubmatchIndex(line); m != nil {
+				for i := 2; i < len(m); i += 2 {
+					if m[i] >= 0 {
+						return offset + m[i], offset + m[i+1], nil
+					}
+				}
+			}
+		}
+		offset += len(line)
+	}
+	return 0, 0, ErrKeyNotFound
+}
+
+// locateXML finds the text of an element by its slash separated path from
+// the root, e.g. project/version skips project/parent/version
+func locateXML(b []byte, key string) (int, int, error) {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	var path []string
+	start := -1
+	for {
+		offset := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return 0, 0, ErrKeyNotFound
+		} else if err != nil {
+			return 0, 0, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name.Local)
+			start = -1
+			if strings.Join(path, "/") == key {
+				start = int(dec.InputOffset())
+			}
+		case xml.EndElement:
+			if start >= 0 && strings.Join(path, "/") == key {
+				s, e := start, offset
+				for s < e && isSpace(b[s]) {
+					s++
+				}
+				for e > s && isSpace(b[e-1]) {
+					e--
+				}
+				return s, e, nil
+			}
+			path = path[:len(path)-1]
+		}
+	}
+}
+
+// locateGo finds a string constant or variable, e.g. `const Version = "1.0.0"`
+// or `Version = "1.0.0"` in a const block
+func locateGo(b []byte, key string) (int, int, error) {
+	re := regexp.MustCompile(`(?m)^\s*(?:(?:const|var)\s+)?` + regexp.QuoteMeta(key) + `(?:\s+string)?\s*=\s*"([^"\n]*)"`)
+	return submatch(re, b)
+}
+
+// locateText returns the trimmed content of the file
+func locateText(b []byte) (int, int) {
+	s, e := 0, len(b)
+	for s < e && isSpace(b[s]) {
+		s++
+	}
+	for e > s && isSpace(b[e-1]) {
+		e--
+	}
+	return s, e
+}
+
+// submatch returns the range of the first matched group
+func submatch(re *regexp.Regexp, b []byte) (int, int, error) {
+	m := re.FindSubmatchIndex(b)
+	if m == nil {
+		return 0, 0, ErrKeyNotFound
+	}
+	for i := 2; i < len(m); i += 2 {
+		if m[i] >= 0 {
+			return m[i], m[i+1], nil
+		}
+	}
+	return 0, 0, ErrKeyNotFound
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
@@ -0,0 +1,225 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		want *File
+		err  bool
+	}{
+		{"package.json", &File{Path: "package.json", Format: "json", Key: "version"}, false},
+		{"Chart.yml", &File{Path: "Chart.yml", Format: "yaml", Key: "version"}, false},
+		{"Cargo.toml", &File{Path: "Cargo.toml", Format: "toml", Key: "package.version"}, false},
+		{"pom.xml", &File{Path: "pom.xml", Format: "xml", Key: "project/version"}, false},
+		{"version.go", &File{Path: "version.go", Format: "go", Key: "Version"}, false},
+		{"VERSION", &File{Path: "VERSION", Format: "text", Key: ""}, false},
+		{"Cargo.toml:toml:workspace.package.version", &File{Path: "Cargo.toml", Format: "toml", Key: "workspace.package.version"}, false},
+		{"app.cfg:yaml", &File{Path: "app.cfg", Format: "yaml", Key: "version"}, false},
+		{"app.cfg:ini", nil, true},
+	}
+	for _, tt := range tests {
+		f, err := Parse(tt.spec)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Parse(%q) expected an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(f, tt.want) {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", tt.spec, f, err, tt.want)
+		}
+	}
+}
+
+func TestLocate(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		key    string
+		doc    string
+		want   string
+		err    bool
+	}{
+		{"json", "json", "version", `{"name": "app", "version": "1.2.3"}`, "1.2.3", false},
+		{
+			"json nested version keys",
+			"json", "version",
+			`{
+  "name": "version",
+  "engines": {"version": "9.9.9"},
+  "files": ["version"],
+  "private": true,
+  "version" : "1.2.3"
+}`,
+			"1.2.3", false,
+		},
+		{"json only nested", "json", "version", `{"config": {"version": "9.9.9"}}`, "", true},
+		{"json not a string", "json", "version", `{"version": 1}`, "", true},
+		{"json custom key", "json", "release", `{"version": "9.9.9", "release": "2.0.0"}`, "2.0.0", false},
+
+		{"yaml", "yaml", "version", "apiVersion: v2\nversion: 1.2.3\n", "1.2.3", false},
+		{"yaml double quoted", "yaml", "version", "version: \"1.2.3\"\n", "1.2.3", false},
+		{"yaml single quoted", "yaml", "version", "version: '1.2.3'\n", "1.2.3", false},
+		{"yaml comment", "yaml", "version", "version: 1.2.3   # bumped by ci\n", "1.2.3", false},
+		{"yaml quoted comment", "yaml", "version", "version: \"1.2.3\" # bumped by ci\n", "1.2.3", false},
+		{"yaml nested", "yaml", "version", "dependencies:\n  - name: redis\n    version: 9.9.9\nversion: 1.2.3\n", "1.2.3", false},
+		{"yaml other key", "yaml", "version", "appVersion: 9.9.9\n", "", true},
+
+		{"toml package", "toml", "package.version", "[package]\nname = \"app\"\nversion = \"1.2.3\"\n", "1.2.3", false},
+		{
+			"toml workspace package",
+			"toml", "package.version",
+			"[workspace.package]\nversion = \"9.9.9\"\n\n[package]\nversion = \"1.2.3\" # release\n",
+			"1.2.3", false,
+		},
+		{
+			"toml workspace key",
+			"toml", "workspace.package.version",
+			"[package]\nversion = \"9.9.9\"\n[workspace.package]\nversion = '1.2.3'\n",
+			"1.2.3", false,
+		},
+		{
+			"toml dependency",
+			"toml", "package.version",
+			"[dependencies]\nserde = { version = \"9.9.9\" }\nversion = \"9.9.8\"\n[package]\nversion = \"1.2.3\"\n",
+			"1.2.3", false,
+		},
+		{"toml top level", "toml", "version", "version = \"1.2.3\"\n[package]\nversion = \"9.9.9\"\n", "1.2.3", false},
+		{"toml missing table", "toml", "package.version", "[workspace.package]\nversion = \"9.9.9\"\n", "", true},
+
+		{
+			"pom",
+			"xml", "project/version",
+			`<?xml version="1.0"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <parent>
+    <version>9.9.9</version>
+  </parent>
+  <dependencies><dependency><version>9.9.8</version></dependency></dependencies>
+  <version>
+    1.2.3
+  </version>
+</project>`,
+			"1.2.3", false,
+		},
+		{
+			"pom parent version",
+			"xml", "project/parent/version",
+			"<project><version>9.9.9</version><parent><version>1.2.3</version></parent></project>",
+			"1.2.3", false,
+		},
+		{"pom inherited version", "xml", "project/version", "<project><parent><version>9.9.9</version></parent></project>", "", true},
+
+		{"go const", "go", "Version", "package main\n\nconst Version = \"1.2.3\"\n", "1.2.3", false},
+		{"go typed var", "go", "Version", "package main\n\nvar Version string = \"1.2.3\"\n", "1.2.3", false},
+		{"go block", "go", "Version", "package main\n\nconst (\n\tName    = \"app\"\n\tVersion = \"1.2.3\"\n)\n", "1.2.3", false},
+		{"go other name", "go", "Version", "package main\n\nconst AppVersion = \"9.9.9\"\n", "", true},
+
+		{"text", "text", "", "\n 1.2.3 \n", "1.2.3", false},
+	}
+	for _, tt := range tests {
+		f := &File{Path: tt.name, Format: tt.format, Key: tt.key}
+		start, end, err := f.locate([]byte(tt.doc))
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, found %q", tt.name, tt.doc[start:end])
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if got := tt.doc[start:end]; got != tt.want {
+			t.Errorf("%s: version = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "semver-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		name string
+		spec string
+		doc  string
+		want string
+	}{
+		{
+			"package.json",
+			"package.json",
+			"{\n  \"name\": \"app\",\n  \"version\": \"1.2.3\",\n  \"engines\": {\"version\": \"1.2.3\"}\n}\n",
+			"{\n  \"name\": \"app\",\n  \"version\": \"2.0.0\",\n  \"engines\": {\"version\": \"1.2.3\"}\n}\n",
+		},
+		{
+			"Chart.yaml",
+			"Chart.yaml",
+			"# chart\nversion: '1.2.3' # chart version\nappVersion: 1.2.3\n",
+			"# chart\nversion: '2.0.0' # chart version\nappVersion: 1.2.3\n",
+		},
+		{
+			"Cargo.toml",
+			"Cargo.toml",
+			"[workspace.package]\nversion = \"1.2.3\"\n\n[package]\nversion = \"1.2.3\"\n",
+			"[workspace.package]\nversion = \"1.2.3\"\n\n[package]\nversion = \"2.0.0\"\n",
+		},
+		{
+			"pom.xml",
+			"pom.xml",
+			"<project>\n  <parent><version>1.2.3</version></parent>\n  <version>1.2.3</version>\n</project>\n",
+			"<project>\n  <parent><version>1.2.3</version></parent>\n  <version>2.0.0</version>\n</project>\n",
+		},
+		{"VERSION", "VERSION", "1.2.3\n", "2.0.0\n"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := ioutil.WriteFile(path, []byte(tt.doc), 0600); err != nil {
+			t.Fatal(err)
+		}
+		f, err := Parse(filepath.Join(dir, tt.spec))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ver, err := f.Read(); err != nil || ver != "1.2.3" {
+			t.Errorf("%s: Read = %q, %v", tt.name, ver, err)
+		}
+		if changed, err := f.Write("2.0.0"); err != nil || !changed {
+			t.Errorf("%s: Write = %v, %v", tt.name, changed, err)
+		}
+		if changed, err := f.Write("2.0.0"); err != nil || changed {
+			t.Errorf("%s: second Write = %v, %v", tt.name, changed, err)
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.want {
+			t.Errorf("%s: file = %q, want %q", tt.name, b, tt.want)
+		}
+	}
+
+	// plain text files are created, other formats are not
+	f, err := Parse(filepath.Join(dir, "RELEASE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write("1.0.0"); err != nil {
+		t.Errorf("Write of a new text file: %v", err)
+	}
+	f, err = Parse(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write("1.0.0"); err == nil || !strings.Contains(err.Error(), "no such file") {
+		t.Errorf("Write of a missing json file = %v, want not found", err)
+	}
+}
@@ -0,0 +1,240 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are the default histogram buckets in seconds
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default registry
+var Default = NewRegistry()
+
+// collector writes metrics in prometheus text format
+type collector interface {
+	write(w io.Writer)
+}
+
+// Registry represents a set of metrics
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []collector
+}
+
+// NewRegistry creates metrics registry
+func NewRegistry() *Registry {
+	return new(Registry)
+}
+
+// register adds a collector to the registry
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo writes all metrics in prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	r.mu.RLock()
+	for _, c := range r.collectors {
+		c.write(&buf)
+	}
+	r.mu.RUnlock()
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP implements http.Handler
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.WriteTo(w)
+}
+
+// desc holds metric name, help and label names
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+// header writes HELP and TYPE lines
+func (d *desc) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, d.typ)
+}
+
+// pairs formats label names and values
+func (d *desc) pairs(values []string, extra ...string) string {
+	parts := make([]string, 0, len(values)+1)
+	for i, v := range values {
+		parts = append(parts, fmt.Sprintf("%s=%q", d.labels[i], v))
+	}
+	parts = append(parts, extra...)
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// series returns the sorted label values of a vector
+func series(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// format a float value
+func format(v float64) string {
+	if math.IsInf(v, +1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// CounterVec represents a set of counters partitioned by labels
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	labels map[string][]string
+	values map[string]float64
+}
+
+// NewCounterVec creates and registers a counter vector
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{name: name, help: help, typ: "counter", labels: labels},
+		labels: make(map[string][]string),
+		values: make(map[string]float64),
+	}
+	r.register(c)
+	return c
+}
+
+// Add increases the counter of label values by v
+func (c *CounterVec) Add(v float64, values ...string) {
+	k := strings.Join(values, "\xff")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.labels[k] = values
+	c.values[k] += v
+}
+
+// Inc increases the counter of label values by one
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(w)
+	for _, k := range series(c.labels) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.pairs(c.labels[k]), format(c.values[k]))
+	}
+}
+
+// histogram holds the observations of a label set
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec represents a set of histograms partitioned by labels
+type HistogramVec struct {
+	desc
+	mu      sync.Mutex
+	buckets []float64
+	labels  map[string][]string
+	values  map[string]*histogram
+}
+
+// NewHistogramVec creates and registers a histogram vector
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+	h := &HistogramVec{
+		desc:    desc{name: name, help: help, typ: "histogram", labels: labels},
+		buckets: sorted,
+		labels:  make(map[string][]string),
+		values:  make(map[string]*histogram),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe adds an observation to the histogram of label values
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	k := strings.Join(values, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	o, ok := h.values[k]
+	if !ok {
+		o = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.labels[k] = values
+		h.values[k] = o
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			o.counts[i]++
+		}
+	}
+	o.count++
+	o.sum += v
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w)
+	for _, k := range series(h.labels) {
+		o := h.values[k]
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.pairs(h.labels[k], fmt.Sprintf("le=%q", format(b))), o.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.pairs(h.labels[k], `le="+Inf"`), o.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.pairs(h.labels[k]), format(o.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.pairs(h.labels[k]), o.count)
+	}
+}
+
+// GaugeFunc represents a gauge whose value is collected on every scrape
+type GaugeFunc struct {
+	desc
+	fn func() (float64, error)
+}
+
+// NewGaugeFunc creates and registers a gauge function, the gauge is omitted
+// from the output when fn returns an error
+func (r *Registry) NewGaugeFunc(name, help string, fn func() (float64, error)) *GaugeFunc {
+	g := &GaugeFunc{
+		desc: desc{name: name, help: help, typ: "gauge"},
+		fn:   fn,
+	}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	v, err := g.fn()
+	if err != nil {
+		return
+	}
+	g.header(w)
+	fmt.Fprintf(w, "%s %s\n", g.name, format(v))
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samuelngs/semver/backend"
+	"github.com/samuelngs/semver/pkg/admin"
+	"github.com/samuelngs/semver/pkg/logger"
+)
+
+// health installs liveness, readiness and backend diagnostics endpoints
+func health(api *gin.Engine, m *backend.Manager) {
+
+	// GET: /healthz
+	api.GET("/healthz", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	// GET: /readyz
+	api.GET("/readyz", func(c *gin.Context) {
+		if err := m.Ping(); err != nil {
+			c.String(http.StatusServiceUnavailable, "%v", err)
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	// GET: /debug/backend
+	api.GET("/debug/backend", func(c *gin.Context) {
+		if !admin.Authorized(c.Request) {
+			c.String(http.StatusForbidden, "administrator privileges required")
+			return
+		}
+		res := gin.H{
+			"backend": m.Name(),
+			"stats":   m.Stats(),
+		}
+		if err := m.Err(); err != nil {
+			res["init_error"] = err.Error()
+		}
+		if err, at := m.LastError(); err != nil {
+			res["last_error"] = err.Error()
+			res["last_error_at"] = at.Format(time.RFC3339)
+		}
+		if err := m.Ping(); err != nil {
+			res["ping"] = err.Error()
+		} else {
+			res["ping"] = "ok"
+		}
+		c.JSON(http.StatusOK, res)
+	})
+
+	// GET: /debug/backend/backup
+	api.GET("/debug/backend/backup", func(c *gin.Context) {
+		if !admin.Authorized(c.Request) {
+			c.String(http.StatusForbidden, "administrator privileges required")
+			return
+		}
+		c.Header("Content-Type", "application/octet-stream")
+		c.Header("Content-Disposition", `attachment; filename="semver-`+time.Now().UTC().Format("20060102T150405Z")+`.db"`)
+		_, err := m.Backup(c.Writer)
+		switch {
+		case err == nil:
+		case !c.Writer.Written():
+			// nothing was streamed yet, report the error instead of an empty file
+			c.Writer.Header().Del("Content-Disposition")
+			c.Writer.Header().Del("Content-Type")
+			status := http.StatusServiceUnavailable
+			if err == backend.ErrNotSupported {
+				status = http.StatusNotImplemented
+			}
+			c.String(status, "%v", err)
+		default:
+			logger.Error("backup failed", logger.Fields{"error": err.Error(), "request_id": logger.RequestID(c)})
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samuelngs/semver/backend"
+	"github.com/samuelngs/semver/pkg/logger"
+)
+
+// access installs the structured access log middleware
+func access(api *gin.Engine, m *backend.Manager) {
+	resolve := route(api)
+	api.Use(func(c *gin.Context) {
+		start := time.Now()
+		id := logger.RequestID(c)
+		c.Next()
+		fields := logger.Fields{
+			"request_id": id,
+			"method":     c.Request.Method,
+			"route":      resolve(c),
+			"path":       c.Request.URL.Path,
+			"status":     c.Writer.Status(),
+			"latency_ms": float64(time.Since(start).Nanoseconds()) / 1e6,
+			"client_ip":  c.ClientIP(),
+			"backend":    m.Name(),
+		}
+		if project := c.Param("id"); project != "" {
+			fields["project"] = project
+		}
+		logger.Info("request", fields)
+	})
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samuelngs/semver/backend"
+	"github.com/samuelngs/semver/pkg/env"
+	"github.com/samuelngs/semver/pkg/metrics"
+)
+
+// instrument installs the request middleware and the prometheus endpoint
+func instrument(api *gin.Engine, m *backend.Manager) {
+	buckets := env.F64s("SEMVER_METRICS_BUCKETS", metrics.DefBuckets)
+	requests := metrics.Default.NewCounterVec(
+		"semver_http_requests_total",
+		"Number of HTTP requests.",
+		"method", "route", "status",
+	)
+	latency := metrics.Default.NewHistogramVec(
+		"semver_http_request_duration_seconds",
+		"Latency of HTTP requests.",
+		buckets,
+		"method", "route",
+	)
+	projects := &projectCount{m: m, interval: env.Duration("SEMVER_METRICS_COUNT_INTERVAL", time.Minute)}
+	metrics.Default.NewGaugeFunc(
+		"semver_projects",
+		"Number of projects in the storage backend.",
+		projects.value,
+	)
+
+	resolve := route(api)
+
+	api.Use(func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := resolve(c)
+		requests.Inc(c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+		latency.Observe(time.Since(start).Seconds(), c.Request.Method, route)
+	})
+
+	path := env.Raw("SEMVER_METRICS_PATH", "/metrics")
+	api.GET(path, gin.WrapH(metrics.Default))
+}
+
+// projectCount caches the number of projects, counting scans every project
+// on some backends and must not run on every scrape
+type projectCount struct {
+	m        *backend.Manager
+	interval time.Duration
+
+	mu    sync.Mutex
+	count float64
+	err   error
+	at    time.Time
+}
+
+// value returns the cached count, it is counted again once the interval has
+// passed
+func (p *projectCount) value() (float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.at.IsZero() || time.Since(p.at) >= p.interval {
+		count, err := p.m.Count()
+		p.count, p.err, p.at = float64(count), err, time.Now()
+	}
+	return p.count, p.err
+}
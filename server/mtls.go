@@ -0,0 +1,101 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v2"
+)
+
+// permissions maps certificate subjects to project access, e.g.
+//
+//	"CN=ci-bot,O=Example":
+//	  e84e9872-fbf7-4d76-b222-68ba1f3e72b3: write
+//	"CN=dashboard":
+//	  "*": read
+type permissions map[string]map[string]string
+
+// loadPermissions reads the permissions file
+func loadPermissions(path string) (permissions, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var perms permissions
+	if err := yaml.Unmarshal(b, &perms); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for subject, projects := range perms {
+		for project, access := range projects {
+			if access != "read" && access != "write" {
+				return nil, fmt.Errorf("%s: %s: %s: access must be read or write", path, subject, project)
+			}
+		}
+	}
+	return perms, nil
+}
+
+// allowed checks if a subject has the access to a project, write access
+// implies read access and "*" matches every project
+func (p permissions) allowed(subjects []string, project, access string) bool {
+	for _, subject := range subjects {
+		projects, ok := p[subject]
+		if !ok {
+			continue
+		}
+		for _, id := range []string{project, "*"} {
+			if a, ok := projects[id]; ok && (a == "write" || a == access) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// request checks if the client certificate of a request has the access to
+// a project
+func (p permissions) request(c *gin.Context, project, access string) bool {
+	var subjects []string
+	if c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) > 0 {
+		cert := c.Request.TLS.VerifiedChains[0][0]
+		subjects = append(subjects, cert.Subject.String(), "CN="+cert.Subject.CommonName)
+	}
+	return p.allowed(subjects, project, access)
+}
+
+// bump checks if a request is a bump of a project or component, bumps are
+// GET requests that change the version and need write access
+func bump(c *gin.Context) bool {
+	path := "/v1/" + c.Param("id")
+	if name := c.Param("name"); name != "" {
+		path += "/components/" + name
+	}
+	return c.Request.URL.Path == path+"/bump"
+}
+
+// authorize installs the client certificate authorization middleware,
+// requests without a project id are not restricted and batches are checked
+// by the v1 router for each project of the batch
+func authorize(api *gin.Engine, perms permissions) {
+	api.Use(func(c *gin.Context) {
+		project := c.Param("id")
+		if project == "" || (project == "batch" && c.Request.Method == "POST" && c.Request.URL.Path == "/v1/batch") {
+			c.Next()
+			return
+		}
+		access := "write"
+		if (c.Request.Method == "GET" || c.Request.Method == "HEAD") && project != "new" && !bump(c) {
+			access = "read"
+		}
+		if project == "new" {
+			project = "*"
+		}
+		if !perms.request(c, project, access) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	})
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthorize(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	api := gin.New()
+	authorize(api, permissions{
+		"CN=dashboard": {"p1": "read"},
+		"CN=ci-bot":    {"p1": "write"},
+	})
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	api.GET("/v1/:id", ok)
+	api.POST("/v1/:id", ok)
+	api.GET("/v1/:id/bump", ok)
+	api.GET("/v1/:id/history", ok)
+	api.GET("/v1/:id/components/:name", ok)
+	api.GET("/v1/:id/components/:name/bump", ok)
+	tests := []struct {
+		subject string
+		method  string
+		path    string
+		want    int
+	}{
+		{"dashboard", "GET", "/v1/p1", http.StatusOK},
+		{"dashboard", "GET", "/v1/p1/history", http.StatusOK},
+		{"dashboard", "GET", "/v1/p1/components/bump", http.StatusOK},
+		{"dashboard", "GET", "/v1/p1/bump", http.StatusForbidden},
+		{"dashboard", "GET", "/v1/p1/components/api/bump", http.StatusForbidden},
+		{"dashboard", "POST", "/v1/p1", http.StatusForbidden},
+		{"dashboard", "GET", "/v1/p2", http.StatusForbidden},
+		{"dashboard", "GET", "/v1/new", http.StatusForbidden},
+		{"ci-bot", "GET", "/v1/p1/bump", http.StatusOK},
+		{"ci-bot", "GET", "/v1/p1/components/api/bump", http.StatusOK},
+		{"ci-bot", "POST", "/v1/p1", http.StatusOK},
+		{"ci-bot", "GET", "/v1/p2/bump", http.StatusForbidden},
+		{"", "GET", "/v1/p1", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.subject != "" {
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: tt.subject}}
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		}
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s as %q = %d, want %d", tt.method, tt.path, tt.subject, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/bsm/ratelimit.v1"
+)
+
+// limiter holds the rate limiter of a client
+type limiter struct {
+	rl   *ratelimit.RateLimiter
+	seen time.Time
+}
+
+// throttle installs the per-client rate limit middleware, idle clients are
+// forgotten every minute until the server is closed
+func (s *Server) throttle(rate int, per time.Duration) {
+	var mu sync.Mutex
+	clients := make(map[string]*limiter)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		t := time.NewTicker(time.Minute)
+		defer t.Stop()
+		for {
+			select {
+			case <-s.quit:
+				return
+			case <-t.C:
+			}
+			mu.Lock()
+			for ip, l := range clients {
+				if time.Since(l.seen) > 10*per+time.Minute {
+					delete(clients, ip)
+				}
+			}
+			mu.Unlock()
+		}
+	}()
+	s.Use(func(c *gin.Context) {
+		ip := c.ClientIP()
+		mu.Lock()
+		l, ok := clients[ip]
+		if !ok {
+			l = &limiter{rl: ratelimit.New(rate, per)}
+			clients[ip] = l
+		}
+		l.seen = time.Now()
+		limited := l.rl.Limit()
+		mu.Unlock()
+		if limited {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+		c.Next()
+	})
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// route returns a resolver of the matched route pattern, gin does not expose
+// it so the pattern is looked up by handler name once every route has been
+// registered, handlers registered on several paths are matched by segments
+func route(api *gin.Engine) func(c *gin.Context) string {
+	var once sync.Once
+	routes := make(map[string][]string)
+	return func(c *gin.Context) string {
+		once.Do(func() {
+			for _, route := range api.Routes() {
+				key := route.Method + " " + route.Handler
+				routes[key] = append(routes[key], route.Path)
+			}
+		})
+		paths := routes[c.Request.Method+" "+c.HandlerName()]
+		if len(paths) == 1 {
+			return paths[0]
+		}
+		for _, path := range paths {
+			if matches(path, c.Request.URL.Path) {
+				return path
+			}
+		}
+		return "unmatched"
+	}
+}
+
+// matches checks if a request path matches a route pattern
+func matches(pattern, path string) bool {
+	ps := strings.Split(strings.Trim(pattern, "/"), "/")
+	rs := strings.Split(strings.Trim(path, "/"), "/")
+	if len(ps) != len(rs) {
+		return false
+	}
+	for i, p := range ps {
+		if !strings.HasPrefix(p, ":") && p != rs[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -2,14 +2,38 @@ package server
 
 import (
 	"log"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/samuelngs/semver/backend"
 	"github.com/samuelngs/semver/handler/v1"
+	"github.com/samuelngs/semver/pkg/env"
+	"github.com/samuelngs/semver/pkg/logger"
 )
 
+// Server represents the api server
+type Server struct {
+	*gin.Engine
+	m  *backend.Manager
+	v1 *v1.Router
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Close stops background workers and closes the storage backend, it should
+// be called once the http server has drained in-flight requests
+func (s *Server) Close() error {
+	close(s.quit)
+	s.wg.Wait()
+	s.v1.Close()
+	return s.m.Close()
+}
+
 // New creates server
-func New(opts ...backend.Client) *gin.Engine {
+func New(opts ...backend.Client) *Server {
 
 	var store backend.Client
 
@@ -22,11 +46,58 @@ func New(opts ...backend.Client) *gin.Engine {
 		log.Fatal("missing storage backend configuration")
 	}
 
+	m := backend.New(store)
+	if err := m.Err(); err != nil {
+		logger.Error("backend initialization failed", logger.Fields{
+			"backend": store.Name(),
+			"error":   err.Error(),
+		})
+	}
+
 	api := gin.New()
 	api.Use(gin.Recovery())
 
+	s := &Server{Engine: api, m: m, quit: make(chan struct{})}
+
+	// structured access log
+	if env.Bool("SEMVER_ACCESS_LOG", true) {
+		access(api, m)
+	}
+
+	// prometheus metrics
+	if env.Bool("SEMVER_METRICS") {
+		instrument(api, m)
+	}
+
+	// client certificate authorization
+	var batches v1.Authorizer
+	if path := env.Raw("SEMVER_TLS_PERMISSIONS"); path != "" {
+		perms, err := loadPermissions(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		authorize(api, perms)
+		batches = perms.request
+	}
+
+	// cross-origin resource sharing
+	if origins := env.Raw("SEMVER_CORS_ORIGINS"); origins != "" {
+		cors(api, strings.Split(origins, ","))
+	}
+
+	// per-client rate limit
+	if rate := env.Int("SEMVER_RATE_LIMIT"); rate > 0 {
+		s.throttle(rate, env.Duration("SEMVER_RATE_PERIOD", time.Second))
+	}
+
+	// health checks and diagnostics
+	health(api, m)
+
 	// version 1
-	v1.New(store, api)
+	s.v1 = v1.New(m, api)
+	if batches != nil {
+		s.v1.Authorize(batches)
+	}
 
-	return api
+	return s
 }
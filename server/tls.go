@@ -0,0 +1,196 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/samuelngs/semver/pkg/env"
+	"github.com/samuelngs/semver/pkg/logger"
+)
+
+// tls protocol versions by name
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// modern cipher suites, forward secret AEAD only
+var modernCiphers = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+}
+
+// certificate keeps the server certificate and reloads it when the files change
+type certificate struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	mod  time.Time
+}
+
+// load reads the certificate and key files
+func (c *certificate) load() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	mod, err := c.modified()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.cert = &cert
+	c.mod = mod
+	c.mu.Unlock()
+	return nil
+}
+
+// modified returns the latest modification time of the certificate files
+func (c *certificate) modified() (time.Time, error) {
+	var mod time.Time
+	for _, f := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(f)
+		if err != nil {
+			return mod, err
+		}
+		if info.ModTime().After(mod) {
+			mod = info.ModTime()
+		}
+	}
+	return mod, nil
+}
+
+// reload reads the certificate again, the previous certificate is kept when
+// the new one cannot be loaded so established connections are never dropped
+func (c *certificate) reload(reason string) {
+	if err := c.load(); err != nil {
+		logger.Error("tls certificate reload failed", logger.Fields{"reason": reason, "error": err.Error()})
+		return
+	}
+	logger.Info("tls certificate reloaded", logger.Fields{"reason": reason})
+}
+
+// watch reloads the certificate on SIGHUP and when the files change until
+// quit is closed
+func (c *certificate) watch(interval time.Duration, quit <-chan struct{}) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-hup:
+			c.reload("signal")
+		case <-tick.C:
+			mod, err := c.modified()
+			c.mu.RLock()
+			changed := err == nil && mod.After(c.mod)
+			c.mu.RUnlock()
+			if changed {
+				c.reload("file change")
+			}
+		}
+	}
+}
+
+// get implements tls.Config.GetCertificate
+func (c *certificate) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// TLS creates the server tls configuration from SEMVER_TLS_* settings, the
+// certificate is reloaded on SIGHUP or when the certificate files change
+// until the server is closed
+func (s *Server) TLS() (*tls.Config, error) {
+	cert := &certificate{
+		certFile: env.Raw("SEMVER_TLS_CERT"),
+		keyFile:  env.Raw("SEMVER_TLS_KEY"),
+	}
+	if err := cert.load(); err != nil {
+		return nil, fmt.Errorf("tls: %v", err)
+	}
+	conf := &tls.Config{
+		GetCertificate: cert.get,
+	}
+	min, ok := tlsVersions[env.Raw("SEMVER_TLS_MIN_VERSION", "1.2")]
+	if !ok {
+		return nil, errors.New("tls: unsupported minimum version")
+	}
+	conf.MinVersion = min
+	ciphers, err := cipherSuites(env.Raw("SEMVER_TLS_CIPHERS", "modern"))
+	if err != nil {
+		return nil, err
+	}
+	conf.CipherSuites = ciphers
+	if ca := env.Raw("SEMVER_TLS_CLIENT_CA"); ca != "" {
+		pem, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("tls: client ca does not contain any certificate")
+		}
+		conf.ClientCAs = pool
+		switch env.Raw("SEMVER_TLS_CLIENT_AUTH", "require") {
+		case "request":
+			conf.ClientAuth = tls.VerifyClientCertIfGiven
+		case "require":
+			conf.ClientAuth = tls.RequireAndVerifyClientCert
+		default:
+			return nil, errors.New("tls: client auth must be request or require")
+		}
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		cert.watch(env.Duration("SEMVER_TLS_RELOAD_INTERVAL", 30*time.Second), s.quit)
+	}()
+	return conf, nil
+}
+
+// cipherSuites resolves a cipher policy {modern, compatible} or a comma
+// separated list of cipher suite names. TLS 1.3 suites are not configurable.
+func cipherSuites(policy string) ([]uint16, error) {
+	switch policy {
+	case "modern":
+		return modernCiphers, nil
+	case "compatible":
+		return nil, nil
+	}
+	names := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		names[s.Name] = s.ID
+	}
+	var ids []uint16
+	for _, name := range strings.Split(policy, ",") {
+		id, ok := names[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("tls: unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}